@@ -7,9 +7,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/analysis"
 	"github.com/mg7d/mg7d/internal/api"
+	"github.com/mg7d/mg7d/internal/auditsink"
 	"github.com/mg7d/mg7d/internal/config"
 	"github.com/mg7d/mg7d/internal/logtail"
 	"github.com/mg7d/mg7d/internal/metrics"
@@ -21,6 +24,7 @@ import (
 )
 
 func main() {
+	startedAt := time.Now()
 	logger, err := zap.NewProduction()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "logger: %v\n", err)
@@ -55,13 +59,45 @@ func main() {
 	instanceName := inst.Name
 
 	snapStore := state.NewSnapshotStore()
-	auditRing := state.NewAuditRing(1024)
+	history := state.NewHistory(cfg.History.MaxSamples)
+	analyzer := analysis.New(history, analysis.OptionsFrom(cfg.Analysis))
+	auditRing := state.NewAuditRing(cfg.Audit.RingSize)
+	var auditLog *state.AuditLog
+	if cfg.Audit.File.Path != "" {
+		auditLog, err = state.OpenAuditLog(auditsink.FileOptions(cfg.Audit.File))
+		if err != nil {
+			logger.Fatal("audit log open failed", zap.Error(err))
+		}
+		if evs, err := auditLog.ReadTail(cfg.Audit.RingSize); err != nil {
+			logger.Warn("audit log reload failed", zap.Error(err))
+		} else {
+			auditRing.Restore(evs)
+		}
+		auditRing.Persist(auditLog, nil)
+	}
+	auditRing.OnError(func(err error) {
+		logger.Error("audit write failed", zap.Error(err))
+	})
+	for _, sc := range cfg.Audit.Sinks {
+		sink, err := auditsink.New(sc, func(err error) {
+			logger.Error("audit sink delivery failed", zap.String("sink", sc.Name), zap.Error(err))
+		})
+		if err != nil {
+			logger.Fatal("audit sink setup failed", zap.String("sink", sc.Name), zap.Error(err))
+		}
+		auditRing.AddSink(sc.Name, sink, auditsink.Filter(sc))
+	}
+	defer auditRing.Close()
 	metricsReg := metrics.NewRegistry(instanceName)
 	metricsReg.RegisterCollectors()
+	metricsReg.RegisterAnalyzer(analyzer)
 
 	policyEngine := policy.NewEngine(instanceName, inst)
 
-	var applier *actions.Applier
+	var (
+		telnetClient *telnet.Client
+		applier      *actions.Applier
+	)
 	if inst.Telnet.Host != "" && inst.Telnet.Port > 0 {
 		telnetCfg := telnet.Config{
 			Host:            inst.Telnet.Host,
@@ -69,7 +105,7 @@ func main() {
 			Password:        inst.Telnet.Password,
 			RateLimitPerSec: inst.Telnet.RateLimitPerSec,
 		}
-		telnetClient := telnet.NewClient(telnetCfg)
+		telnetClient = telnet.NewClient(telnetCfg)
 		go telnetClient.Run(ctx)
 		applier = actions.NewApplier(telnetClient, auditRing, 32)
 		if len(inst.Actions.Baseline) > 0 {
@@ -78,16 +114,113 @@ func main() {
 		go applier.Run(ctx)
 	}
 
-	tailer, err := logtail.NewTailer(inst.LogPath, logtail.Options{})
+	policyEngine.OnError(func(err error) {
+		logger.Error("policy state write failed", zap.Error(err))
+	})
+	startupActions, err := policyEngine.LoadState()
 	if err != nil {
-		logger.Fatal("tailer create failed", zap.String("instance", instanceName), zap.Error(err))
+		logger.Warn("policy state load failed", zap.Error(err))
+	}
+	for _, a := range startupActions {
+		if applier == nil {
+			logger.Warn("startup action skipped: telnet not configured", zap.String("action_id", a.ID()), zap.String("reason", a.Reason()))
+			continue
+		}
+		if err := applier.Enqueue(ctx, a); err != nil {
+			logger.Warn("applier enqueue failed", zap.String("action_id", a.ID()), zap.Error(err))
+		}
+	}
+
+	source, err := logtail.NewSource(inst.Source.Type, inst.Source.Path, logtail.Options{
+		Format:    inst.Source.Format,
+		QueueSize: inst.Source.QueueSize,
+		Overflow:  inst.Source.Overflow,
+		Syslog: logtail.SyslogOptions{
+			Listen:    inst.Source.Syslog.Listen,
+			Protocol:  inst.Source.Syslog.Protocol,
+			AppNames:  inst.Source.Syslog.AppNames,
+			Hostnames: inst.Source.Syslog.Hostnames,
+		},
+	})
+	if err != nil {
+		logger.Fatal("log source create failed", zap.String("instance", instanceName), zap.Error(err))
+	}
+	metricsReg.RegisterSource(source)
+	linesCh := source.Lines()
+	go func() {
+		if err := source.Run(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("log source exited", zap.String("instance", instanceName), zap.Error(err))
+		}
+	}()
+
+	// HTTP server: status API, /healthz and (if enabled) /metrics
+	deps := api.Deps{
+		Instance:   instanceName,
+		SourceType: inst.Source.Type,
+		SourcePath: inst.Source.Path,
+		Snapshots:  snapStore,
+		History:    history,
+		Source:     source,
+		Policy:     policyEngine,
+		Telnet:     telnetClient,
+		Applier:    applier,
+		Audit:      auditRing,
+		AuditLog:   auditLog,
+		AuthToken:  cfg.API.AuthToken,
+		ReadToken:  cfg.API.ReadToken,
+		AuthExempt: cfg.API.AuthExempt,
+
+		DisableDashboard: cfg.API.DisableDashboard,
+		Events: api.EventOptions{
+			BufferSize: cfg.API.Events.BufferSize,
+			MaxClients: cfg.API.Events.MaxClients,
+			Heartbeat:  seconds(cfg.API.Events.HeartbeatSeconds),
+		},
+		Health: api.HealthOptions{
+			MaxLineAge:       seconds(cfg.Health.MaxLineAgeSeconds),
+			MaxSnapshotAge:   seconds(cfg.Health.MaxSnapshotAgeSeconds),
+			StartupGrace:     seconds(cfg.Health.StartupGraceSeconds),
+			ApplierQueueWarn: cfg.Health.ApplierQueueWarn,
+			ApplierQueueFail: cfg.Health.ApplierQueueFail,
+			TelnetOptional:   cfg.Health.TelnetOptional,
+		},
+		StartedAt: startedAt,
+	}
+	if cfg.Metrics.Enable {
+		deps.Metrics = metricsReg.Handler()
+		deps.MetricsPath = cfg.Metrics.Path
+	}
+	if cfg.API.Unprotected() {
+		logger.Warn("api has no auth_token or read_token; anyone who can reach it can read status and audit", zap.String("listen", cfg.API.Listen))
+	}
+	srv := api.NewServer(cfg.API.Listen, deps)
+	auditRing.AddSink("events", srv.AuditSink(), state.AuditFilter{})
+	policyEngine.OnChange(srv.PublishPolicy)
+	if t := cfg.API.TLS; t.Enabled() {
+		err := srv.EnableTLS(api.TLSOptions{
+			CertFile:     t.CertFile,
+			KeyFile:      t.KeyFile,
+			ClientCAFile: t.ClientCAFile,
+			ClientAuth:   t.ClientAuth,
+			MinVersion:   t.MinVersion,
+			OnReload: func(err error) {
+				if err != nil {
+					logger.Error("api tls reload failed; keeping previous certificate", zap.Error(err))
+					return
+				}
+				logger.Info("api tls certificate reloaded", zap.String("cert_file", t.CertFile))
+			},
+		})
+		if err != nil {
+			logger.Fatal("api tls setup failed", zap.Error(err))
+		}
 	}
-	linesCh := tailer.Lines()
 	go func() {
-		if err := tailer.Run(ctx); err != nil && ctx.Err() == nil {
-			logger.Error("tailer exited", zap.String("instance", instanceName), zap.Error(err))
+		if err := srv.ListenAndServe(); err != nil && ctx.Err() == nil {
+			logger.Error("http server failed", zap.Error(err))
 		}
 	}()
+	defer srv.Shutdown(context.Background())
 
 	// Parser goroutine: consume lines -> update snapshot -> metrics -> policy -> applier
 	go func() {
@@ -99,17 +232,21 @@ func main() {
 				if !ok {
 					return
 				}
-				snap, ok, parseErr := parser.ParseTimeLine(line)
+				snap, ok, parseErr := parser.ParseTimeLineAt(line.Text, line.Time)
+				metricsReg.ObserveLineTime(lineTime(line, snap, ok && parseErr == nil))
 				if parseErr != nil {
-					logger.Debug("parse error", zap.String("line", line), zap.Error(parseErr))
+					logger.Debug("parse error", zap.String("line", line.Text), zap.Error(parseErr))
 					continue
 				}
 				if !ok {
 					continue
 				}
 				snapStore.Update(snap)
+				srv.PublishSnapshot(snap)
+				history.Add(snap)
+				signals := analyzer.Update(snap)
 				metricsReg.UpdateFromSnapshot(snap)
-				if policyActions := policyEngine.Evaluate(snap); applier != nil && len(policyActions) > 0 {
+				if policyActions := policyEngine.Evaluate(policy.Input{Snapshot: snap, Signals: signals}); applier != nil && len(policyActions) > 0 {
 					for _, a := range policyActions {
 						if err := applier.Enqueue(ctx, a); err != nil {
 							logger.Warn("applier enqueue failed", zap.String("action_id", a.ID()), zap.Error(err))
@@ -120,18 +257,24 @@ func main() {
 		}
 	}()
 
-	// Metrics HTTP server
-	if cfg.Metrics.Enable {
-		srv := api.NewMetricsServer(cfg.API.Listen, cfg.Metrics.Path, metricsReg.Handler())
-		go func() {
-			if err := srv.ListenAndServe(); err != nil && ctx.Err() == nil {
-				logger.Error("metrics server failed", zap.Error(err))
-			}
-		}()
-		defer srv.Shutdown(context.Background())
-	}
-
-	logger.Info("agent running", zap.String("instance", instanceName), zap.String("log_path", inst.LogPath))
+	logger.Info("agent running", zap.String("instance", instanceName), zap.String("source", inst.Source.Type), zap.String("log_path", inst.Source.Path))
 	<-ctx.Done()
 	logger.Info("agent shutting down")
 }
+
+// lineTime is when line was written, as far as the agent can tell: the source timestamp
+// (docker, journald), a time parsed from the Time: line itself, or else when it was read.
+func lineTime(line logtail.Line, snap state.Snapshot, parsed bool) time.Time {
+	if !line.Time.IsZero() {
+		return line.Time
+	}
+	if parsed && !snap.Timestamp.Equal(snap.ParsedAt) {
+		return snap.Timestamp
+	}
+	return line.ReadAt
+}
+
+// seconds converts a config value in seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mg7d/mg7d/pkg/client"
+)
+
+const defaultAgentAddr = "http://127.0.0.1:9090"
+
+// ctlConfig is the optional ctl config file listing the agents to talk to, one per
+// game server instance:
+//
+//	agents:
+//	  - name: main
+//	    addr: https://10.0.0.5:9090
+//	    token_env: MG7D_MAIN_TOKEN
+//	    ca_file: /etc/mg7d/ca.crt
+type ctlConfig struct {
+	Agents []agentConfig `yaml:"agents"`
+}
+
+type agentConfig struct {
+	Name     string `yaml:"name"`      // label in output; defaults to addr
+	Addr     string `yaml:"addr"`      // base URL of the agent's HTTP server
+	Token    string `yaml:"token"`     // bearer token; prefer token_env
+	TokenEnv string `yaml:"token_env"` // environment variable holding the token
+	CAFile   string `yaml:"ca_file"`   // PEM CAs for the agent's certificate
+	CertFile string `yaml:"cert_file"` // client certificate for mutual TLS
+	KeyFile  string `yaml:"key_file"`
+}
+
+// agentFlags are the connection flags shared by commands that call the agent API.
+type agentFlags struct {
+	config   string
+	agents   string
+	addr     string
+	token    string
+	caFile   string
+	certFile string
+	keyFile  string
+}
+
+func (f *agentFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "ctl-config", "", "ctl config file (default $MG7D_CTL_CONFIG, else ~/.config/mg7d/ctl.yaml if present)")
+	fs.StringVar(&f.agents, "agent", "", "comma-separated agent names from the ctl config (default all)")
+	fs.StringVar(&f.addr, "addr", "", "agent base URL (default $MG7D_ADDR, else the ctl config, else "+defaultAgentAddr+")")
+	fs.StringVar(&f.token, "token", "", "bearer token (default $MG7D_TOKEN); visible to other local users, prefer the variable")
+	fs.StringVar(&f.caFile, "ca-file", "", "PEM CAs for the agent's certificate")
+	fs.StringVar(&f.certFile, "cert-file", "", "client certificate for mutual TLS")
+	fs.StringVar(&f.keyFile, "key-file", "", "client key for mutual TLS")
+}
+
+// target is one agent to query.
+type target struct {
+	name string
+	c    *client.Client
+}
+
+// targets resolves the agents to talk to. An address from -addr or MG7D_ADDR selects
+// that single agent, else the ctl config lists them. An agent's own token or token_env
+// wins over -token and MG7D_TOKEN, which only fill in for agents without one, so a
+// token meant for one agent is never sent to the others.
+func (f *agentFlags) targets(timeout time.Duration) ([]target, error) {
+	token := firstNonEmpty(f.token, os.Getenv("MG7D_TOKEN"))
+	flagTLS := agentConfig{CAFile: f.caFile, CertFile: f.certFile, KeyFile: f.keyFile}
+
+	var agents []agentConfig
+	if addr := firstNonEmpty(f.addr, os.Getenv("MG7D_ADDR")); addr != "" {
+		if f.agents != "" {
+			return nil, fmt.Errorf("-agent selects from the ctl config and cannot be combined with an address")
+		}
+		a := flagTLS
+		a.Addr = addr
+		agents = []agentConfig{a}
+	} else {
+		cfg, err := loadCtlConfig(f.config)
+		if err != nil {
+			return nil, err
+		}
+		if agents, err = selectAgents(cfg.Agents, f.agents); err != nil {
+			return nil, err
+		}
+		if len(agents) == 0 {
+			a := flagTLS
+			a.Addr = defaultAgentAddr
+			agents = []agentConfig{a}
+		}
+	}
+
+	out := make([]target, 0, len(agents))
+	for _, a := range agents {
+		a.CAFile = firstNonEmpty(f.caFile, a.CAFile)
+		a.CertFile = firstNonEmpty(f.certFile, a.CertFile)
+		a.KeyFile = firstNonEmpty(f.keyFile, a.KeyFile)
+		hc, err := httpClient(a, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", a.label(), err)
+		}
+		tok := a.Token
+		if tok == "" && a.TokenEnv != "" {
+			tok = os.Getenv(a.TokenEnv)
+		}
+		if tok == "" {
+			tok = token
+		}
+		c, err := client.New(a.Addr, client.WithToken(tok), client.WithHTTPClient(hc))
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", a.label(), err)
+		}
+		out = append(out, target{name: a.label(), c: c})
+	}
+	return out, nil
+}
+
+func (a agentConfig) label() string {
+	return firstNonEmpty(a.Name, a.Addr)
+}
+
+// loadCtlConfig reads the ctl config from path, $MG7D_CTL_CONFIG or the default
+// location. A missing default file is not an error.
+func loadCtlConfig(path string) (ctlConfig, error) {
+	var cfg ctlConfig
+	explicit := true
+	if path == "" {
+		path = os.Getenv("MG7D_CTL_CONFIG")
+	}
+	if path == "" {
+		explicit = false
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return cfg, nil
+		}
+		path = filepath.Join(dir, "mg7d", "ctl.yaml")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("ctl config: %w", err)
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("ctl config %s: %w", path, err)
+	}
+	for i, a := range cfg.Agents {
+		if a.Addr == "" {
+			return cfg, fmt.Errorf("ctl config %s: agents[%d].addr is required", path, i)
+		}
+	}
+	return cfg, nil
+}
+
+func selectAgents(all []agentConfig, names string) ([]agentConfig, error) {
+	if names == "" {
+		return all, nil
+	}
+	var out []agentConfig
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, a := range all {
+			if a.Name == name {
+				out = append(out, a)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("agent %q is not in the ctl config", name)
+		}
+	}
+	return out, nil
+}
+
+// httpClient builds an HTTP client with the agent's TLS settings. timeout 0 is for
+// streaming requests.
+func httpClient(a agentConfig, timeout time.Duration) (*http.Client, error) {
+	if a.CAFile == "" && a.CertFile == "" && a.KeyFile == "" {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if a.CAFile != "" {
+		pem, err := os.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", a.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (a.CertFile == "") != (a.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if a.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = cfg
+	return &http.Client{Timeout: timeout, Transport: tr}, nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
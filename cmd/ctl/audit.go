@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func runAudit(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "usage: %s audit list|follow|export|verify [flags]\n", programName)
+		return exitUsage
+	}
+	switch args[0] {
+	case "list":
+		return runAuditList(args[1:], stdout, stderr)
+	case "follow":
+		return runAuditFollow(args[1:], stdout, stderr)
+	case "export":
+		return runAuditExport(args[1:], stdout, stderr)
+	case "verify":
+		return runAuditVerify(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "%s audit: unknown command %q\n", programName, args[0])
+		return exitUsage
+	}
+}
+
+// runAuditVerify checks the audit log hash chain. Exit 0: intact; 1: tampering
+// detected; 2: usage or read error.
+func runAuditVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfgPath := fs.String("config", "", "agent config; audit.file provides path, backups and HMAC key")
+	file := fs.String("file", "", "audit log path (overrides config)")
+	backups := fs.Int("backups", 0, "rotated files to include (default from config, else 5)")
+	keyEnv := fs.String("hmac-key-env", "", "environment variable holding the HMAC key (overrides config)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var af config.AuditFile
+	if *cfgPath != "" {
+		cfg, err := config.Load(*cfgPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+		af = cfg.Audit.File
+	}
+	if *file != "" {
+		af.Path = *file
+	}
+	if *backups > 0 {
+		af.MaxBackups = *backups
+	}
+	if af.MaxBackups == 0 {
+		af.MaxBackups = 5
+	}
+	if *keyEnv != "" {
+		af.HMACKey = os.Getenv(*keyEnv)
+		if af.HMACKey == "" {
+			fmt.Fprintf(stderr, "%s: %s is empty\n", programName, *keyEnv)
+			return exitUsage
+		}
+	}
+	if af.Path == "" {
+		fmt.Fprintf(stderr, "%s: audit log path required (-file or -config with audit.file.path)\n", programName)
+		return exitUsage
+	}
+
+	res, err := state.VerifyAuditLog(af.Path, af.MaxBackups, []byte(af.HMACKey))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	for _, w := range res.Warnings {
+		fmt.Fprintf(stdout, "warning: %s\n", w)
+	}
+	for _, p := range res.Problems {
+		fmt.Fprintf(stdout, "FAIL: %s\n", p)
+	}
+	if res.Unchained > 0 {
+		fmt.Fprintf(stdout, "note: %d leading records predate hash chaining and were not checked\n", res.Unchained)
+	}
+	if res.Anchor != "" {
+		fmt.Fprintf(stdout, "note: oldest retained record chains to a rotated-away record (prev_hash %s)\n", res.Anchor)
+	}
+	if !res.OK() {
+		fmt.Fprintf(stdout, "audit log %s: %d problem(s) in %d records across %d file(s)\n", af.Path, len(res.Problems), res.Records, len(res.Files))
+		return exitFailed
+	}
+	fmt.Fprintf(stdout, "audit log %s: ok, %d records across %d file(s)\n", af.Path, res.Records, len(res.Files))
+	return exitOK
+}
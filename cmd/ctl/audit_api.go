@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/pkg/client"
+)
+
+// auditFilterFlags are the audit event filters shared by list, follow and export.
+type auditFilterFlags struct {
+	actionID   string
+	actionType string
+	status     string
+	instance   string
+	policy     string
+	since      string
+	until      string
+}
+
+func (f *auditFilterFlags) register(fs *flag.FlagSet, times bool) {
+	fs.StringVar(&f.actionID, "action-id", "", "only this action ID")
+	fs.StringVar(&f.actionType, "type", "", "only this action type: "+strings.Join(config.AuditActionTypes, ", "))
+	fs.StringVar(&f.status, "status", "", "only this status: "+strings.Join(config.AuditStatuses, ", "))
+	fs.StringVar(&f.instance, "instance", "", "only this instance")
+	fs.StringVar(&f.policy, "policy", "", "only actions from this policy, e.g. fps_guard")
+	if times {
+		fs.StringVar(&f.since, "since", "", "events at or after: RFC 3339 time, or a duration before now such as 24h")
+		fs.StringVar(&f.until, "until", "", "events before: RFC 3339 time, or a duration before now")
+	}
+}
+
+func (f *auditFilterFlags) query(now time.Time) (client.AuditQuery, error) {
+	q := client.AuditQuery{
+		ActionID:   f.actionID,
+		ActionType: f.actionType,
+		Status:     f.status,
+		Instance:   f.instance,
+		Policy:     f.policy,
+	}
+	if q.ActionType != "" && !slices.Contains(config.AuditActionTypes, q.ActionType) {
+		return q, fmt.Errorf("-type %q invalid (%s)", q.ActionType, strings.Join(config.AuditActionTypes, ", "))
+	}
+	if q.Status != "" && !slices.Contains(config.AuditStatuses, q.Status) {
+		return q, fmt.Errorf("-status %q invalid (%s)", q.Status, strings.Join(config.AuditStatuses, ", "))
+	}
+	var err error
+	if q.Since, err = parseSince(f.since, now); err != nil {
+		return q, fmt.Errorf("-since: %w", err)
+	}
+	if q.Until, err = parseSince(f.until, now); err != nil {
+		return q, fmt.Errorf("-until: %w", err)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		return q, fmt.Errorf("-until must be after -since")
+	}
+	return q, nil
+}
+
+// match applies the filters to a streamed event (follow).
+func (f *auditFilterFlags) match(ev client.AuditEvent) bool {
+	return (f.actionID == "" || ev.ActionID == f.actionID) &&
+		(f.actionType == "" || ev.ActionType == f.actionType) &&
+		(f.status == "" || ev.Status == f.status) &&
+		(f.instance == "" || ev.Instance == f.instance) &&
+		(f.policy == "" || ev.Policy == f.policy)
+}
+
+// parseSince accepts an RFC 3339 time or a duration before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("duration must be positive")
+		}
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// singleTarget resolves the agent flags to exactly one agent.
+func singleTarget(af *agentFlags, timeout time.Duration) (target, error) {
+	targets, err := af.targets(timeout)
+	if err != nil {
+		return target{}, err
+	}
+	if len(targets) != 1 {
+		return target{}, fmt.Errorf("the ctl config lists %d agents; pick one with -agent", len(targets))
+	}
+	return targets[0], nil
+}
+
+// agentError prints err and returns the exit code for a failed agent call.
+func agentError(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+	var e *client.Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusBadRequest {
+		return exitUsage
+	}
+	return exitAgent
+}
+
+// runAuditList prints matching audit events from the agent, newest first by default.
+func runAuditList(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var af agentFlags
+	af.register(fs)
+	var ff auditFilterFlags
+	ff.register(fs, true)
+	limit := fs.Int("limit", 50, "maximum events to print")
+	asc := fs.Bool("asc", false, "oldest first")
+	format := fs.String("o", "table", "output format: table, json or jsonl")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-request timeout")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	q, err := ff.query(time.Now())
+	if err == nil {
+		err = checkFormat(*format, "table", "json", "jsonl")
+	}
+	if err == nil && *limit < 1 {
+		err = fmt.Errorf("-limit must be at least 1")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	t, err := singleTarget(&af, *timeout)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	q.Desc = !*asc
+	var evs []client.AuditEvent
+	for len(evs) < *limit {
+		q.Limit = min(*limit-len(evs), 1000)
+		page, err := t.c.Audit(context.Background(), q)
+		if err != nil {
+			return agentError(stderr, err)
+		}
+		evs = append(evs, page.Events...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	switch *format {
+	case "json":
+		err = writeStructured(stdout, "json", evs)
+	case "jsonl":
+		err = writeJSONL(stdout, evs)
+	default:
+		writeAuditTable(stdout, evs)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// runAuditFollow prints audit events as the agent records them, until interrupted.
+// It reconnects after a disconnect and fills in events missed in between.
+func runAuditFollow(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit follow", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var af agentFlags
+	af.register(fs)
+	var ff auditFilterFlags
+	ff.register(fs, false)
+	since := fs.String("since", "", "first print events from this far back (duration) or time (RFC 3339)")
+	format := fs.String("o", "text", "output format: text or jsonl")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	q, err := ff.query(time.Now())
+	if err == nil {
+		q.Since, err = parseSince(*since, time.Now())
+	}
+	if err == nil {
+		err = checkFormat(*format, "text", "jsonl")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	t, err := singleTarget(&af, 0) // the stream has no deadline
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f := &follower{c: t.c, filter: &ff, print: func(ev client.AuditEvent) error {
+		if *format == "jsonl" {
+			return writeJSONL(stdout, []client.AuditEvent{ev})
+		}
+		_, err := fmt.Fprintln(stdout, auditLine(ev))
+		return err
+	}}
+	if !q.Since.IsZero() {
+		if err := t.c.AuditStream(ctx, q, f.emit); err != nil {
+			return agentError(stderr, err)
+		}
+	}
+	if err := f.run(ctx, stderr); err != nil {
+		return agentError(stderr, err)
+	}
+	return exitOK
+}
+
+// follower streams audit events and uses sequence numbers to backfill events
+// missed while disconnected.
+type follower struct {
+	c       *client.Client
+	filter  *auditFilterFlags
+	print   func(client.AuditEvent) error
+	lastSeq uint64
+}
+
+func (f *follower) emit(ev client.AuditEvent) error {
+	f.lastSeq = ev.Seq
+	if !f.filter.match(ev) {
+		return nil
+	}
+	return f.print(ev)
+}
+
+// run follows the stream until ctx is done. The first connection must succeed;
+// later disconnects are retried with backoff.
+func (f *follower) run(ctx context.Context, stderr io.Writer) error {
+	backoff := time.Second
+	for connected := false; ; {
+		var stopErr error // from handling an event rather than from the stream
+		err := f.c.Events(ctx, []string{"audit"}, func(e client.Event) error {
+			connected, backoff = true, time.Second
+			var ev client.AuditEvent
+			if stopErr = e.Decode(&ev); stopErr != nil {
+				return stopErr
+			}
+			if stopErr = f.backfill(ctx, ev.Seq); stopErr != nil {
+				return stopErr
+			}
+			stopErr = f.emit(ev)
+			return stopErr
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if stopErr != nil {
+			return stopErr
+		}
+		var ae *client.Error
+		if !connected || (errors.As(err, &ae) && ae.StatusCode != 503) {
+			return err
+		}
+		if err == nil {
+			err = errors.New("stream closed")
+		}
+		fmt.Fprintf(stderr, "%s: %v; reconnecting in %s\n", programName, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// backfill emits events between the last one seen and seq. A seq at or below the
+// last one means the agent restarted and numbering began again.
+func (f *follower) backfill(ctx context.Context, seq uint64) error {
+	if f.lastSeq == 0 || seq <= f.lastSeq+1 {
+		return nil
+	}
+	q := client.AuditQuery{Limit: 1000, Cursor: strconv.FormatUint(f.lastSeq, 10)}
+	for {
+		page, err := f.c.Audit(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, ev := range page.Events {
+			if ev.Seq >= seq {
+				return nil
+			}
+			if err := f.emit(ev); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// runAuditExport writes every matching event, oldest first, as JSONL or CSV.
+func runAuditExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var af agentFlags
+	af.register(fs)
+	var ff auditFilterFlags
+	ff.register(fs, true)
+	format := fs.String("o", "jsonl", "output format: jsonl or csv")
+	out := fs.String("out", "", "write to this file instead of stdout")
+	timeout := fs.Duration("timeout", 5*time.Minute, "export timeout")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	q, err := ff.query(time.Now())
+	if err == nil {
+		err = checkFormat(*format, "jsonl", "csv")
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	t, err := singleTarget(&af, *timeout)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+		defer f.Close()
+		w = f
+	}
+	var write func(client.AuditEvent) error
+	var cw *csv.Writer
+	if *format == "csv" {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+		write = func(ev client.AuditEvent) error { return cw.Write(csvRecord(ev)) }
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(ev client.AuditEvent) error { return enc.Encode(ev) }
+	}
+	n := 0
+	err = t.c.AuditStream(context.Background(), q, func(ev client.AuditEvent) error {
+		n++
+		return write(ev)
+	})
+	if cw != nil {
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	}
+	if err != nil {
+		return agentError(stderr, err)
+	}
+	if *out != "" {
+		fmt.Fprintf(stderr, "exported %d events to %s\n", n, *out)
+	}
+	return exitOK
+}
+
+var csvHeader = []string{"seq", "action_id", "action_type", "status", "instance", "policy", "caller", "reason", "commands", "changes", "error", "queued_at", "sent_at", "done_at"}
+
+func csvRecord(ev client.AuditEvent) []string {
+	changes := make([]string, 0, len(ev.Changes))
+	for _, c := range ev.Changes {
+		changes = append(changes, fmt.Sprintf("%s=%s->%s", c.Pref, c.OldValue, c.NewValue))
+	}
+	return []string{
+		strconv.FormatUint(ev.Seq, 10), ev.ActionID, ev.ActionType, ev.Status, ev.Instance, ev.Policy, ev.Caller,
+		ev.Reason, strings.Join(ev.Commands, "; "), strings.Join(changes, "; "), ev.Error,
+		csvTime(ev.QueuedAt), csvTime(ev.SentAt), csvTime(ev.DoneAt),
+	}
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func writeJSONL(w io.Writer, evs []client.AuditEvent) error {
+	enc := json.NewEncoder(w)
+	for _, ev := range evs {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAuditTable(w io.Writer, evs []client.AuditEvent) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEQ\tTIME\tSTATUS\tTYPE\tSOURCE\tDETAIL")
+	for _, ev := range evs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", ev.Seq, eventTime(ev).Local().Format("2006-01-02 15:04:05"), ev.Status, ev.ActionType, firstNonEmpty(ev.Policy, ev.Caller, "-"), eventDetail(ev))
+	}
+	tw.Flush()
+}
+
+// auditLine formats one event for follow, which cannot align columns in advance.
+func auditLine(ev client.AuditEvent) string {
+	return fmt.Sprintf("%s #%d %-8s %-15s %s %s", eventTime(ev).Local().Format("2006-01-02 15:04:05"), ev.Seq, ev.Status, ev.ActionType, firstNonEmpty(ev.Policy, ev.Caller, "-"), eventDetail(ev))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+	"github.com/mg7d/mg7d/pkg/client"
+)
+
+func auditAgent(t *testing.T) (string, *state.AuditRing) {
+	t.Helper()
+	addr, ring := testAgent(t, "main", time.Second)
+	t.Setenv("MG7D_CTL_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Setenv("MG7D_ADDR", addr)
+	t.Setenv("MG7D_TOKEN", "read-secret")
+	now := time.Now()
+	ring.Append(state.AuditEvent{ActionID: "a1", ActionType: "SetGamePref", Status: "success", Policy: "fps_guard", QueuedAt: now.Add(-3 * time.Hour), DoneAt: now.Add(-3 * time.Hour),
+		Changes: []state.PrefChange{{Pref: "MaxSpawnedZombies", OldValue: "64", NewValue: "48"}}, Commands: []string{"setpref MaxSpawnedZombies 48"}})
+	ring.Append(state.AuditEvent{ActionID: "a2", ActionType: "Say", Status: "failure", Error: "telnet: closed", Reason: "warn, players", QueuedAt: now.Add(-time.Hour)})
+	ring.Append(state.AuditEvent{ActionID: "a3", ActionType: "RestoreBaseline", Status: "success", Policy: "fps_guard", QueuedAt: now.Add(-time.Minute)})
+	return addr, ring
+}
+
+func TestAuditList(t *testing.T) {
+	auditAgent(t)
+
+	code, out, errOut := runCtl(t, "audit", "list")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "3 ") || !strings.Contains(lines[3], "MaxSpawnedZombies") {
+		t.Errorf("table:\n%s", out)
+	}
+
+	code, out, _ = runCtl(t, "audit", "list", "-policy", "fps_guard", "-asc", "-limit", "1", "-o", "jsonl")
+	if code != exitOK || strings.Count(out, "\n") != 1 || !strings.Contains(out, `"action_id":"a1"`) {
+		t.Errorf("filtered: %d\n%s", code, out)
+	}
+	if code, _, _ := runCtl(t, "audit", "list", "-status", "bogus"); code != exitUsage {
+		t.Errorf("bad status: exit %d", code)
+	}
+	if code, _, _ := runCtl(t, "audit", "list", "-since", "1h", "-until", "2h"); code != exitUsage {
+		t.Errorf("until before since: exit %d", code)
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	auditAgent(t)
+
+	code, out, errOut := runCtl(t, "audit", "export", "-since", "2h", "-o", "csv")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	recs, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || recs[0][0] != "seq" || recs[1][1] != "a2" || recs[1][7] != "warn, players" || recs[1][10] != "telnet: closed" || recs[1][13] != "" {
+		t.Errorf("csv: %q", recs)
+	}
+
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	code, _, errOut = runCtl(t, "audit", "export", "-until", "30m", "-out", file)
+	if code != exitOK || !strings.Contains(errOut, "exported 2 events") {
+		t.Errorf("jsonl to file: %d %s", code, errOut)
+	}
+	code, out, _ = runCtl(t, "audit", "export", "-until", "30m", "-type", "SetGamePref", "-o", "csv")
+	if code != exitOK || !strings.Contains(out, "MaxSpawnedZombies=64->48") {
+		t.Errorf("changes column: %s", out)
+	}
+}
+
+func TestAuditFollowBackfill(t *testing.T) {
+	addr, ring := auditAgent(t)
+	c, err := client.New(addr, client.WithToken("read-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan string, 10)
+	f := &follower{
+		c:       c,
+		filter:  &auditFilterFlags{status: "success"},
+		print:   func(ev client.AuditEvent) error { got <- ev.ActionID; return nil },
+		lastSeq: 1, // seen a1; a2 and a3 were recorded while disconnected
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.run(ctx, &strings.Builder{}) }()
+	for {
+		st, err := c.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Events.Clients == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ring.Append(state.AuditEvent{ActionID: "a4", ActionType: "Say", Status: "failure"})
+	ring.Append(state.AuditEvent{ActionID: "a5", ActionType: "Say", Status: "success"})
+
+	var ids []string
+	for len(ids) < 2 {
+		select {
+		case id := <-got:
+			ids = append(ids, id)
+		case <-ctx.Done():
+			t.Fatalf("got %v", ids)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "a3,a5" || f.lastSeq != 5 {
+		t.Errorf("followed %v, last seq %d", ids, f.lastSeq)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/telnet"
+)
+
+// redacted replaces secret values in `config explain` output.
+const redacted = "<redacted>"
+
+// secretKeys are config keys whose values are never printed.
+var secretKeys = map[string]bool{"password": true, "auth_token": true, "read_token": true, "hmac_key": true}
+
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "usage: %s config validate|explain [flags] <agent.yaml>\n", programName)
+		return exitUsage
+	}
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(args[1:], stdout, stderr)
+	case "explain":
+		return runConfigExplain(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "%s config: unknown command %q\n", programName, args[0])
+		return exitUsage
+	}
+}
+
+// validation is the result of `config validate -o json`.
+type validation struct {
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`   // the agent refuses to start
+	Warnings []string `json:"warnings"` // the agent starts, but probably not as intended
+}
+
+// runConfigValidate loads a config with the agent's loader and reports every problem.
+// Exit 0: valid (warnings allowed unless -strict); 1: problems found; 2: usage error or
+// unreadable file.
+func runConfigValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	format := fs.String("o", "text", "output format: text or json")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(pos) != 1 {
+		fmt.Fprintf(stderr, "usage: %s config validate [-strict] [-o text|json] <agent.yaml>\n", programName)
+		return exitUsage
+	}
+	if err := checkFormat(*format, "text", "json"); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	path := pos[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	res := validation{File: path, Errors: []string{}, Warnings: config.UnknownKeys(data)}
+	cfg, err := config.Load(path)
+	if err != nil {
+		res.Errors = configProblems(err)
+	} else {
+		res.Warnings = append(res.Warnings, configWarnings(cfg)...)
+	}
+	if res.Warnings == nil {
+		res.Warnings = []string{}
+	}
+	res.Valid = len(res.Errors) == 0 && (!*strict || len(res.Warnings) == 0)
+
+	if *format == "json" {
+		if err := writeStructured(stdout, "json", res); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+	} else {
+		for _, e := range res.Errors {
+			fmt.Fprintf(stdout, "%s: error: %s\n", path, e)
+		}
+		for _, w := range res.Warnings {
+			fmt.Fprintf(stdout, "%s: warning: %s\n", path, w)
+		}
+		if len(res.Errors)+len(res.Warnings) == 0 {
+			fmt.Fprintf(stdout, "%s: ok\n", path)
+		} else {
+			fmt.Fprintf(stdout, "%s: %s, %s\n", path, plural(len(res.Errors), "error"), plural(len(res.Warnings), "warning"))
+		}
+	}
+	if !res.Valid {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// configProblems splits a config.Load error into one message per problem.
+func configProblems(err error) []string {
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		out := make([]string, len(te.Errors))
+		for i, msg := range te.Errors {
+			out[i] = "parse config: " + msg
+		}
+		return out
+	}
+	errs := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	}
+	out := make([]string, len(errs))
+	for i, e := range errs {
+		out[i] = strings.TrimPrefix(e.Error(), "config: ")
+	}
+	return out
+}
+
+// configWarnings reports settings the agent accepts but that are probably mistakes.
+func configWarnings(cfg *config.Config) []string {
+	var out []string
+	for i, inst := range cfg.Instances {
+		if i > 0 {
+			out = append(out, fmt.Sprintf("instances[%d] %q is not run: the agent uses the first instance only", i, inst.Name))
+			continue
+		}
+		g := inst.Policy.FPSGuard
+		if g == nil || !g.Enabled {
+			continue
+		}
+		if inst.Telnet.Host == "" {
+			out = append(out, fmt.Sprintf("instances[%d].policy.fps_guard is enabled but telnet is not configured: actions are never sent", i))
+		}
+		if len(inst.Actions.Baseline) == 0 {
+			out = append(out, fmt.Sprintf("instances[%d].actions.baseline is empty: RestoreBaseline changes nothing after a throttle", i))
+		}
+	}
+	if cfg.API.Unprotected() {
+		out = append(out, fmt.Sprintf("api.listen %s is not a loopback address and neither a token nor a required client certificate protects it", cfg.API.Listen))
+	}
+	return out
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// runConfigExplain prints the configuration the agent would run with: every key, with
+// the defaults of config.Validate, the FPS guard and the telnet client filled in and
+// secrets redacted. Exit 0: printed; 1: the config is invalid; 2: usage or read error.
+func runConfigExplain(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(pos) != 1 {
+		fmt.Fprintf(stderr, "usage: %s config explain <agent.yaml>\n", programName)
+		return exitUsage
+	}
+	path := pos[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		for _, p := range configProblems(err) {
+			fmt.Fprintf(stderr, "%s: error: %s\n", path, p)
+		}
+		return exitFailed
+	}
+	doc, err := explainConfig(cfg, data)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	fmt.Fprintf(stdout, "# Effective configuration of %s: defaults filled in, secrets redacted.\n", path)
+	fmt.Fprintln(stdout, `# "default": not set in the file. "built-in": not configurable.`)
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	if err := enc.Close(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// explainConfig returns cfg as an annotated YAML document; data is the file it was
+// loaded from, used to tell set values from defaults.
+func explainConfig(cfg *config.Config, data []byte) (*yaml.Node, error) {
+	var telnets []telnet.Config
+	for i := range cfg.Instances {
+		inst := &cfg.Instances[i]
+		if inst.Policy.FPSGuard != nil {
+			g := policy.FPSGuardDefaults(*inst.Policy.FPSGuard)
+			inst.Policy.FPSGuard = &g
+		}
+		// As the agent builds it: only these fields have config keys.
+		t := telnet.Config{
+			Host:            inst.Telnet.Host,
+			Port:            inst.Telnet.Port,
+			Password:        inst.Telnet.Password,
+			RateLimitPerSec: inst.Telnet.RateLimitPerSec,
+		}
+		telnets = append(telnets, t.WithDefaults())
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var fileRoot *yaml.Node
+	if len(file.Content) > 0 {
+		fileRoot = file.Content[0]
+	}
+	annotate(&doc, fileRoot)
+
+	if insts := mappingValue(&doc, "instances"); insts != nil {
+		for i, n := range insts.Content {
+			if i > 0 {
+				n.HeadComment = "not run: the agent uses the first instance only"
+			}
+			if t := mappingValue(n, "telnet"); t != nil && i < len(telnets) {
+				addBuiltins(t, telnets[i])
+			}
+		}
+	}
+	redact(&doc, "")
+	return &doc, nil
+}
+
+// annotate marks the scalars of n that the file does not set, or sets to a value that
+// a default replaced (e.g. rate_limit_per_sec: 0). file is the matching file node or nil.
+func annotate(n, file *yaml.Node) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			annotate(c, file)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			annotate(n.Content[i+1], mappingValue(file, n.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			var f *yaml.Node
+			if file != nil && file.Kind == yaml.SequenceNode && i < len(file.Content) {
+				f = file.Content[i]
+			}
+			annotate(c, f)
+		}
+	case yaml.ScalarNode:
+		switch {
+		case file == nil || file.Kind != yaml.ScalarNode || file.Tag == "!!null":
+			n.LineComment = "default"
+		case !sameScalar(n.Value, file.Value):
+			n.LineComment = "default, " + strconv.Quote(file.Value) + " in file"
+		}
+	}
+}
+
+// sameScalar compares scalars as numbers when both are, so 2 and 2.0 are equal.
+func sameScalar(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa == fb
+	}
+	return a == b
+}
+
+// addBuiltins appends the telnet client settings that have no config key.
+func addBuiltins(n *yaml.Node, c telnet.Config) {
+	for _, kv := range [][2]string{
+		{"command_timeout", c.CommandTimeout.String()},
+		{"reconnect_min", c.ReconnectMin.String()},
+		{"reconnect_max", c.ReconnectMax.String()},
+		{"circuit_break_after", strconv.Itoa(c.CircuitBreakAfter)},
+		{"circuit_break_window", c.CircuitBreakWindow.String()},
+	} {
+		n.Content = append(n.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: kv[0]},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: kv[1], LineComment: "built-in"})
+	}
+}
+
+// redact replaces secret values: secretKeys, webhook header values and webhook URLs.
+// key is the mapping key n is the value of.
+func redact(n *yaml.Node, key string) {
+	switch {
+	case n.Kind == yaml.ScalarNode && secretKeys[key] && n.Value != "":
+		n.Value, n.Tag, n.LineComment = redacted, "!!str", ""
+	case n.Kind == yaml.ScalarNode && key == "url" && n.Value != "":
+		n.Value = redactURL(n.Value)
+	case n.Kind == yaml.MappingNode && key == "headers":
+		for i := 1; i < len(n.Content); i += 2 {
+			n.Content[i].Value, n.Content[i].Tag = redacted, "!!str"
+		}
+	case n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			redact(n.Content[i+1], n.Content[i].Value)
+		}
+	default:
+		for _, c := range n.Content {
+			redact(c, "")
+		}
+	}
+}
+
+// redactURL keeps only the scheme and host of a webhook URL: Slack, Discord and
+// similar hooks carry their secret in the user info, path or query.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// mappingValue returns the value of key in mapping n (or in the document n wraps).
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n != nil && n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
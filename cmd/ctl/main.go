@@ -1,12 +1,79 @@
-// Package main is the mg7d ctl CLI (stub for Phase 0-3).
+// Package main is the mg7d ctl CLI.
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
+// Exit codes shared by all subcommands.
+const (
+	exitOK      = 0
+	exitFailed  = 1 // the check ran and found a problem
+	exitUsage   = 2 // bad arguments or unreadable input
+	exitAgent   = 3 // an agent could not be reached or rejected the request
+	programName = "mg7d-ctl"
+)
+
 func main() {
-	fmt.Fprintln(os.Stdout, "mg7d ctl (stub); status endpoint not implemented in Phase 0-3)")
-	os.Exit(0)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "status":
+		return runStatus(args[1:], stdout, stderr)
+	case "audit":
+		return runAudit(args[1:], stdout, stderr)
+	case "replay":
+		return runReplay(args[1:], stdout, stderr)
+	case "config":
+		return runConfig(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "%s: unknown command %q\n", programName, args[0])
+		usage(stderr)
+		return exitUsage
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, `Usage: %s <command> [flags]
+
+Commands:
+  status         show FPS, players, policy, telnet and health of running agents
+  audit list     list audit events from an agent, with filters
+  audit follow   print audit events as an agent records them
+  audit export   write audit events in a time range as JSONL or CSV
+  audit verify   check the hash chain of the persistent audit log
+  replay         run the parser and policies over a recorded log and print what would happen
+  config validate  report every problem in an agent config
+  config explain   print the effective agent config with defaults filled in
+
+Run "%s <command> -h" for command flags.
+`, programName, programName)
+}
+
+// parseArgs parses fs from args and returns the positional arguments. Unlike
+// fs.Parse, flags may also follow them ("replay agent.log -config agent.yaml").
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return pos, nil
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
 }
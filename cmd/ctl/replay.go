@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/analysis"
+	"github.com/mg7d/mg7d/internal/api"
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/logtail"
+	"github.com/mg7d/mg7d/internal/parser"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// replayRecord is one line of `replay -o jsonl` output.
+type replayRecord struct {
+	At         time.Time         `json:"at"`
+	Kind       string            `json:"kind"` // snapshot, transition, action or summary
+	Snapshot   *api.Snapshot     `json:"snapshot,omitempty"`
+	Transition *replayTransition `json:"transition,omitempty"`
+	Action     *api.AuditEvent   `json:"action,omitempty"`
+	Summary    *replaySummary    `json:"summary,omitempty"`
+}
+
+// replayTransition is a change of policy state or of an analysis signal.
+type replayTransition struct {
+	Subject string `json:"subject"` // fps_guard, memory_leak or fps_decline
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+type replaySummary struct {
+	Snapshots        int            `json:"snapshots"`
+	Skipped          int            `json:"skipped"` // unparseable or out-of-order Time lines
+	Untimed          int            `json:"untimed"` // snapshots with neither a timestamp nor an uptime, placed -interval apart
+	First            time.Time      `json:"first"`
+	Last             time.Time      `json:"last"`
+	MinFPS           float64        `json:"min_fps"`
+	ThrottledSeconds float64        `json:"throttled_seconds"`
+	Actions          map[string]int `json:"actions"` // by action type
+}
+
+// runReplay feeds a recorded log through the agent's parser, analyzer and policy
+// engine on a virtual clock and prints the snapshots, state transitions and the
+// actions that would have been sent. Nothing is sent over telnet and the policy state
+// file is not touched. Exit 0: replayed; 2: usage or read error.
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfgPath := fs.String("config", "", "agent config (required)")
+	instName := fs.String("instance", "", "instance whose policies to run (default the first)")
+	format := fs.String("format", "", "log format: raw or docker (default the instance's source.format)")
+	interval := fs.Duration("interval", 30*time.Second, "time between snapshots whose line carries neither a timestamp nor an uptime")
+	start := fs.String("start", "", "virtual time of the first snapshot without a timestamp, RFC3339 (default: the last one falls on the file's modification time)")
+	snapshots := fs.Bool("snapshots", true, "print every snapshot, not only transitions and actions")
+	out := fs.String("o", "text", "output format: text or jsonl")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s replay [flags] <logfile>\n", programName)
+		fs.PrintDefaults()
+	}
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(pos) != 1 || *cfgPath == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	path := pos[0]
+	if err := checkFormat(*out, "text", "jsonl"); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(stderr, "%s: -interval must be positive\n", programName)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	inst, err := replayInstance(cfg, *instName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	if *format == "" {
+		*format = inst.Source.Format
+	}
+	if *format != logtail.FormatRaw && *format != logtail.FormatDocker {
+		fmt.Fprintf(stderr, "%s: -format must be raw or docker\n", programName)
+		return exitUsage
+	}
+	opts := logtail.Options{Format: *format}
+
+	var first time.Time
+	if *start != "" {
+		if first, err = time.Parse(time.RFC3339, *start); err != nil {
+			fmt.Fprintf(stderr, "%s: -start: %v\n", programName, err)
+			return exitUsage
+		}
+	} else if first, err = defaultReplayStart(path, opts, *interval); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	defer f.Close()
+
+	r := newReplayer(cfg, inst, first, *interval)
+	emit := replayTextWriter(stdout, *snapshots)
+	if *out == "jsonl" {
+		emit = replayJSONLWriter(stdout, *snapshots)
+	}
+	err = logtail.ReadLines(f, opts, func(line logtail.Line) error {
+		return r.line(line, emit)
+	})
+	if err == nil {
+		err = emit(replayRecord{At: r.clock, Kind: "summary", Summary: &r.summary})
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	if n := r.summary.Untimed; n > 0 {
+		fmt.Fprintf(stderr, "%s: warning: %d of %d snapshots have neither a timestamp nor a Time: uptime; they were placed %s apart (-interval)\n",
+			programName, n, r.summary.Snapshots, *interval)
+	}
+	return exitOK
+}
+
+func replayInstance(cfg *config.Config, name string) (config.Instance, error) {
+	if name == "" {
+		return cfg.Instances[0], nil
+	}
+	for _, inst := range cfg.Instances {
+		if inst.Name == name {
+			return inst, nil
+		}
+	}
+	return config.Instance{}, fmt.Errorf("instance %q is not in the config", name)
+}
+
+// defaultReplayStart places the snapshots of a log without timestamps so the last one
+// falls on the file's modification time, i.e. when the server last wrote it. If the
+// log has timestamps, the snapshots before the first one end just before it.
+func defaultReplayStart(path string, opts logtail.Options, interval time.Duration) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Replay the clock from an arbitrary origin and shift it into place.
+	origin := time.Unix(0, 0)
+	clk := replayClock{next: origin, interval: interval}
+	var start time.Time
+	seen := false
+	errFound := errors.New("found")
+	err = logtail.ReadLines(f, opts, func(line logtail.Line) error {
+		snap, ok, src := clk.parse(line)
+		if !ok {
+			return nil
+		}
+		if src == timeFromLog {
+			start = snap.Timestamp
+			if seen {
+				start = start.Add(-interval - clk.last.Sub(origin))
+			}
+			return errFound
+		}
+		clk.advance(snap.Timestamp)
+		seen = true
+		return nil
+	})
+	switch {
+	case err == errFound:
+		return start, nil
+	case err != nil:
+		return time.Time{}, err
+	case !seen:
+		return fi.ModTime().Truncate(time.Second), nil
+	}
+	return fi.ModTime().Add(-clk.last.Sub(origin)).Truncate(time.Second), nil
+}
+
+// Where a snapshot's virtual time came from.
+const (
+	timeFromInterval = iota // previous snapshot plus -interval
+	timeFromUptime          // previous snapshot plus the growth of the Time: uptime
+	timeFromLog             // container or journal timestamp, or a date in the Time line
+)
+
+// replayClock assigns virtual times to snapshots: the log's own timestamp, else the
+// previous snapshot plus how far the server uptime in the Time line advanced, else the
+// previous snapshot plus interval.
+type replayClock struct {
+	last      time.Time // time of the previous snapshot
+	next      time.Time // time of the first snapshot, then last plus interval
+	interval  time.Duration
+	uptime    time.Duration // of the previous Time line that had one
+	hasUptime bool
+}
+
+// parse parses a Time line at its virtual time and reports where that time came from.
+func (c *replayClock) parse(line logtail.Line) (state.Snapshot, bool, int) {
+	at, src := line.Time, timeFromLog
+	if at.IsZero() {
+		at, src = c.next, timeFromInterval
+		if up, ok := parser.Uptime(line.Text); ok {
+			if c.hasUptime {
+				d := up - c.uptime
+				if d < 0 {
+					d = up // the server restarted
+				}
+				at = c.last.Add(d)
+			}
+			c.uptime, c.hasUptime, src = up, true, timeFromUptime
+		}
+	}
+	snap, ok, err := parser.ParseTimeLineAt(line.Text, at)
+	if err != nil {
+		return state.Snapshot{}, false, src
+	}
+	if ok && !snap.Timestamp.Equal(at) {
+		src = timeFromLog // the line carries a date
+	}
+	return snap, ok, src
+}
+
+// advance records the time of a replayed snapshot.
+func (c *replayClock) advance(t time.Time) {
+	c.last = t
+	c.next = t.Add(c.interval)
+}
+
+// replayer is the agent's parser goroutine with a virtual clock and a simulated
+// applier.
+type replayer struct {
+	engine   *policy.Engine
+	history  *state.History
+	analyzer *analysis.Analyzer
+	applier  *actions.Applier
+
+	clock   time.Time // timestamp of the last snapshot
+	clk     replayClock
+	guard   string // fps_guard state as printed in transitions
+	signals analysis.Signals
+	summary replaySummary
+}
+
+func newReplayer(cfg *config.Config, inst config.Instance, first time.Time, interval time.Duration) *replayer {
+	// Never read or write the live agent's state file.
+	inst.Policy.StateFile = ""
+	r := &replayer{
+		engine:  policy.NewEngine(inst.Name, inst),
+		history: state.NewHistory(cfg.History.MaxSamples),
+		applier: actions.NewApplier(nil, nil, 1),
+		clk:     replayClock{next: first, interval: interval},
+		summary: replaySummary{Actions: make(map[string]int)},
+	}
+	r.analyzer = analysis.New(r.history, analysis.OptionsFrom(cfg.Analysis))
+	r.applier.SetBaseline(inst.Actions.Baseline)
+	r.engine.SetClock(func() time.Time { return r.clock })
+	r.guard = r.guardState()
+	return r
+}
+
+func (r *replayer) line(line logtail.Line, emit func(replayRecord) error) error {
+	if !strings.HasPrefix(strings.TrimSpace(line.Text), "Time:") {
+		return nil
+	}
+	snap, ok, src := r.clk.parse(line)
+	if !ok || snap.Timestamp.Before(r.clock) {
+		r.summary.Skipped++
+		return nil
+	}
+	snap.ParsedAt = snap.Timestamp
+	if r.summary.Snapshots > 0 && r.guard != "normal" {
+		r.summary.ThrottledSeconds += snap.Timestamp.Sub(r.clock).Seconds()
+	}
+	r.clock = snap.Timestamp
+	r.clk.advance(snap.Timestamp)
+	if src == timeFromInterval {
+		r.summary.Untimed++
+	}
+
+	r.history.Add(snap)
+	sig := r.analyzer.Update(snap)
+	acts := r.engine.Evaluate(policy.Input{Snapshot: snap, Signals: sig})
+
+	r.summary.Snapshots++
+	if r.summary.Snapshots == 1 || snap.FPS < r.summary.MinFPS {
+		r.summary.MinFPS = snap.FPS
+	}
+	if r.summary.First.IsZero() {
+		r.summary.First = snap.Timestamp
+	}
+	r.summary.Last = snap.Timestamp
+
+	if err := emit(replayRecord{At: r.clock, Kind: "snapshot", Snapshot: api.SnapshotOf(snap, r.clock)}); err != nil {
+		return err
+	}
+	var trans []replayTransition
+	if g := r.guardState(); g != r.guard {
+		trans = append(trans, replayTransition{Subject: policy.PolicyFPSGuard, From: r.guard, To: g})
+		r.guard = g
+	}
+	if sig.MemoryLeak != r.signals.MemoryLeak {
+		trans = append(trans, replayTransition{Subject: "memory_leak", From: signalState(r.signals.MemoryLeak), To: signalState(sig.MemoryLeak)})
+	}
+	if sig.FPSDecline != r.signals.FPSDecline {
+		trans = append(trans, replayTransition{Subject: "fps_decline", From: signalState(r.signals.FPSDecline), To: signalState(sig.FPSDecline)})
+	}
+	r.signals = sig
+	for i := range trans {
+		if err := emit(replayRecord{At: r.clock, Kind: "transition", Transition: &trans[i]}); err != nil {
+			return err
+		}
+	}
+	for _, a := range acts {
+		ev, err := r.applier.Simulate(a)
+		if err != nil {
+			return err
+		}
+		ev.QueuedAt = r.clock
+		ev.Status = "simulated"
+		r.summary.Actions[ev.ActionType]++
+		wire := api.AuditEventOf(ev)
+		if err := emit(replayRecord{At: r.clock, Kind: "action", Action: &wire}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guardState is "disabled", "normal" or "throttled <step>/<steps>".
+func (r *replayer) guardState() string {
+	st, ok := r.engine.FPSGuardStatus(r.clock)
+	switch {
+	case !ok:
+		return "disabled"
+	case !st.Throttled:
+		return "normal"
+	default:
+		return fmt.Sprintf("throttled %d/%d", st.Step+1, st.Steps)
+	}
+}
+
+func signalState(on bool) string {
+	if on {
+		return "detected"
+	}
+	return "clear"
+}
+
+func replayJSONLWriter(w io.Writer, snapshots bool) func(replayRecord) error {
+	enc := json.NewEncoder(w)
+	return func(rec replayRecord) error {
+		if rec.Kind == "snapshot" && !snapshots {
+			return nil
+		}
+		return enc.Encode(rec)
+	}
+}
+
+func replayTextWriter(w io.Writer, snapshots bool) func(replayRecord) error {
+	return func(rec replayRecord) error {
+		at := rec.At.Local().Format("2006-01-02 15:04:05")
+		var err error
+		switch rec.Kind {
+		case "snapshot":
+			if !snapshots {
+				return nil
+			}
+			s := rec.Snapshot
+			_, err = fmt.Fprintf(w, "%s  snapshot    fps=%.1f players=%d zombies=%d entities=%d heap=%.0fMB rss=%.0fMB\n",
+				at, s.FPS, s.Players, s.Zombies, s.Entities, s.HeapMB, s.RSSMB)
+		case "transition":
+			t := rec.Transition
+			_, err = fmt.Fprintf(w, "%s  transition  %s: %s -> %s\n", at, t.Subject, t.From, t.To)
+		case "action":
+			ev := rec.Action
+			if _, err = fmt.Fprintf(w, "%s  action      %s %s (%s) %s\n", at, ev.ActionType, ev.ActionID, firstNonEmpty(ev.Policy, "-"), ev.Reason); err != nil {
+				return err
+			}
+			for _, c := range ev.Commands {
+				if _, err = fmt.Fprintf(w, "%21s> %s\n", "", c); err != nil {
+					return err
+				}
+			}
+			for _, c := range ev.Changes {
+				if _, err = fmt.Fprintf(w, "%21s  %s: %s -> %s\n", "", c.Pref, orDash(c.OldValue), c.NewValue); err != nil {
+					return err
+				}
+			}
+		case "summary":
+			err = writeReplaySummary(w, rec.Summary)
+		default:
+			err = errors.New("unknown replay record " + rec.Kind)
+		}
+		return err
+	}
+}
+
+func writeReplaySummary(w io.Writer, s *replaySummary) error {
+	fmt.Fprintf(w, "\n%d snapshots", s.Snapshots)
+	if s.Snapshots > 0 {
+		fmt.Fprintf(w, " from %s to %s (%s), min FPS %.1f",
+			s.First.Local().Format("2006-01-02 15:04:05"), s.Last.Local().Format("2006-01-02 15:04:05"),
+			formatAge(s.Last.Sub(s.First)), s.MinFPS)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(w, ", %d lines skipped", s.Skipped)
+	}
+	fmt.Fprintf(w, "\nthrottled for %s\n", formatAge(time.Duration(s.ThrottledSeconds*float64(time.Second))))
+	types := make([]string, 0, len(s.Actions))
+	for t := range s.Actions {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	if len(types) == 0 {
+		_, err := fmt.Fprintln(w, "no actions")
+		return err
+	}
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%d %s", s.Actions[t], t)
+	}
+	_, err := fmt.Fprintf(w, "actions: %s\n", strings.Join(parts, ", "))
+	return err
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
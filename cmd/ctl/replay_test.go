@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const replayConfig = `instances:
+  - name: main
+    log_path: /var/log/7dtd/output_log.txt
+    source:
+      format: %FORMAT%
+    policy:
+      fps_guard:
+        enabled: true
+        threshold_low: 25
+        threshold_restore: 40
+        require_low_samples: 3
+        sample_window_samples: 3
+        restore_stable_seconds: 60
+        cooldown_seconds: 60
+        throttle_profile: default
+      state_file: %STATE%
+    actions:
+      baseline:
+        MaxSpawnedZombies: "50"
+      throttle_profiles:
+        default:
+          steps:
+            - pref: MaxSpawnedZombies
+              value: "30"
+            - pref: MaxSpawnedZombies
+              value: "20"
+`
+
+// writeReplayConfig writes an agent config whose state file must stay untouched.
+func writeReplayConfig(t *testing.T, format string) (cfgPath, statePath string) {
+	t.Helper()
+	dir := t.TempDir()
+	statePath = filepath.Join(dir, "policy.json")
+	cfg := strings.NewReplacer("%FORMAT%", format, "%STATE%", statePath).Replace(replayConfig)
+	cfgPath = filepath.Join(dir, "agent.yaml")
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return cfgPath, statePath
+}
+
+func decodeReplay(t *testing.T, out string) []replayRecord {
+	t.Helper()
+	var recs []replayRecord
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		var rec replayRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("%v: %s", err, sc.Text())
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestReplay(t *testing.T) {
+	cfgPath, statePath := writeReplayConfig(t, "raw")
+	// Log file first and flags after it, as in the docs.
+	code, stdout, stderr := runCtl(t, "replay", filepath.Join("..", "..", "testdata", "replay_fps.log"),
+		"-config", cfgPath, "-start", "2024-05-01T12:00:00Z", "-o", "jsonl", "-snapshots=false")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var got []string
+	var summary *replaySummary
+	for _, rec := range decodeReplay(t, stdout) {
+		offset := rec.At.Sub(start)
+		switch rec.Kind {
+		case "snapshot":
+			t.Errorf("snapshot printed with -snapshots=false")
+		case "transition":
+			got = append(got, offset.String()+" "+rec.Transition.Subject+" "+rec.Transition.To)
+		case "action":
+			a := rec.Action
+			got = append(got, offset.String()+" "+a.ActionType+" "+strings.Join(a.Commands, ";"))
+			if a.Status != "simulated" {
+				t.Errorf("status = %q", a.Status)
+			}
+		case "summary":
+			summary = rec.Summary
+		}
+	}
+	// The lines' uptimes are a minute apart. Low FPS from Time 3: throttle on the 3rd
+	// low sample, step after the 60s cooldown, restore 60s after FPS recovers at Time 8.
+	want := []string{
+		"5m0s fps_guard throttled 1/2",
+		"5m0s SetGamePref setpref MaxSpawnedZombies 30",
+		"6m0s fps_guard throttled 2/2",
+		"6m0s SetGamePref setpref MaxSpawnedZombies 20",
+		"9m0s fps_guard normal",
+		"9m0s RestoreBaseline setpref MaxSpawnedZombies 50",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("timeline:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if summary == nil || summary.Snapshots != 13 || summary.Untimed != 0 || summary.MinFPS != 15 || summary.ThrottledSeconds != 240 ||
+		summary.Actions["SetGamePref"] != 2 || summary.Actions["RestoreBaseline"] != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("replay touched the policy state file: %v", err)
+	}
+}
+
+func TestReplayDockerTimestamps(t *testing.T) {
+	cfgPath, _ := writeReplayConfig(t, "docker")
+	var b strings.Builder
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, fps := range []int{50, 20, 18, 17} {
+		// Irregular spacing: the container timestamps, not -interval, set the clock.
+		at := start.Add(time.Duration(i*i) * 10 * time.Second).Format(time.RFC3339Nano)
+		b.WriteString(`{"log":"Time: ` + at + ` FPS: ` + strconv.Itoa(fps) + `\n","stream":"stdout","time":"` + at + `"}` + "\n")
+	}
+	b.WriteString("garbage\n")
+	path := filepath.Join(t.TempDir(), "container-json.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCtl(t, "replay", "-config", cfgPath, "-o", "jsonl", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var actionAt time.Time
+	var snaps int
+	for _, rec := range decodeReplay(t, stdout) {
+		switch rec.Kind {
+		case "snapshot":
+			snaps++
+		case "action":
+			actionAt = rec.At
+		}
+	}
+	if snaps != 4 {
+		t.Errorf("snapshots = %d, want 4", snaps)
+	}
+	if want := start.Add(90 * time.Second); !actionAt.Equal(want) {
+		t.Errorf("throttle at %v, want %v", actionAt, want)
+	}
+}
+
+func TestReplayUptimeClock(t *testing.T) {
+	cfgPath, _ := writeReplayConfig(t, "raw")
+	// Uptimes in minutes; the server restarts after the third line.
+	log := "Time: 10.00m FPS: 50\nTime: 10.50m FPS: 50\nTime: 12.00m FPS: 50\nnoise\nTime: 0.25m FPS: 50\n"
+	path := filepath.Join(t.TempDir(), "output_log.txt")
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCtl(t, "replay", "-config", cfgPath, "-o", "jsonl", path)
+	if code != exitOK || stderr != "" {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var got []string
+	for _, rec := range decodeReplay(t, stdout) {
+		if rec.Kind == "snapshot" {
+			got = append(got, mtime.Sub(rec.At).String())
+		}
+	}
+	// The last snapshot falls on the modification time.
+	if want := "2m15s 1m45s 15s 0s"; strings.Join(got, " ") != want {
+		t.Errorf("snapshots before mtime: %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestReplayUntimedWarns(t *testing.T) {
+	cfgPath, _ := writeReplayConfig(t, "raw")
+	path := filepath.Join(t.TempDir(), "output_log.txt")
+	if err := os.WriteFile(path, []byte("Time: FPS: 50\nTime: FPS: 40\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := runCtl(t, "replay", "-config", cfgPath, "-o", "jsonl", "-start", "2024-05-01T12:00:00Z", "-interval", "1m", path)
+	if code != exitOK || !strings.Contains(stderr, "2 of 2 snapshots") {
+		t.Errorf("exit %d, stderr %q", code, stderr)
+	}
+	recs := decodeReplay(t, stdout)
+	if last := recs[len(recs)-1]; last.Summary == nil || last.Summary.Untimed != 2 ||
+		!last.Summary.Last.Equal(time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC)) {
+		t.Errorf("summary = %+v", last.Summary)
+	}
+}
+
+func TestReplayUsage(t *testing.T) {
+	if code, _, _ := runCtl(t, "replay", filepath.Join("..", "..", "testdata", "replay_fps.log")); code != exitUsage {
+		t.Errorf("without -config: exit %d, want %d", code, exitUsage)
+	}
+	cfgPath, _ := writeReplayConfig(t, "raw")
+	if code, _, _ := runCtl(t, "replay", "-config", cfgPath, filepath.Join(t.TempDir(), "missing.log")); code != exitUsage {
+		t.Errorf("missing log: exit %d, want %d", code, exitUsage)
+	}
+}
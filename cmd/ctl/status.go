@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mg7d/mg7d/pkg/client"
+)
+
+// instanceStatus is one agent's entry in `status` output.
+type instanceStatus struct {
+	Agent    string                 `json:"agent"`
+	Healthy  bool                   `json:"healthy"`
+	Problems []string               `json:"problems,omitempty"`
+	Error    string                 `json:"error,omitempty"` // set when the agent could not be queried
+	Status   *client.StatusResponse `json:"status,omitempty"`
+	Health   *client.HealthResponse `json:"health,omitempty"`
+	Recent   []client.AuditEvent    `json:"recent_actions,omitempty"`
+}
+
+// runStatus queries each agent and prints its state. Exit 0: all healthy; 1: a
+// component fails (or warns, with -strict); 3: an agent could not be queried.
+func runStatus(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var af agentFlags
+	af.register(fs)
+	format := fs.String("o", "table", "output format: table, json or yaml")
+	actions := fs.Int("actions", 5, "recent actions to show per instance (0 to skip)")
+	strict := fs.Bool("strict", false, "treat warnings as unhealthy")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-request timeout")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if err := checkFormat(*format, "table", "json", "yaml"); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+	targets, err := af.targets(*timeout)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	ctx := context.Background()
+	results := make([]instanceStatus, len(targets))
+	for i, t := range targets {
+		results[i] = queryStatus(ctx, t, *actions, *strict)
+	}
+
+	switch *format {
+	case "table":
+		writeStatusTable(stdout, results, time.Now())
+	default:
+		if err := writeStructured(stdout, *format, results); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+	}
+
+	code := exitOK
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			return exitAgent
+		case !r.Healthy:
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+func queryStatus(ctx context.Context, t target, actions int, strict bool) instanceStatus {
+	res := instanceStatus{Agent: t.name}
+	st, err := t.c.Status(ctx)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = st
+	h, err := t.c.Health(ctx)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Health = h
+
+	names := make([]string, 0, len(h.Components))
+	for name := range h.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := h.Components[name]
+		if c.Status == "fail" || (strict && c.Status == "warn") {
+			res.Problems = append(res.Problems, fmt.Sprintf("%s %s: %s", name, c.Status, c.Message))
+		}
+	}
+	res.Healthy = len(res.Problems) == 0
+
+	if actions > 0 {
+		// Skip API access denials: they are not actions and can crowd out the rest.
+		page, err := t.c.Audit(ctx, client.AuditQuery{Desc: true, Limit: 10 * actions})
+		if err != nil && !client.IsStatus(err, http.StatusNotFound) {
+			res.Problems = append(res.Problems, "audit: "+err.Error())
+		}
+		if page != nil {
+			for _, ev := range page.Events {
+				if ev.ActionType == "APIAccess" {
+					continue
+				}
+				res.Recent = append(res.Recent, ev)
+				if len(res.Recent) == actions {
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+func writeStatusTable(w io.Writer, results []instanceStatus, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE\tHEALTH\tFPS\tPLAYERS\tSNAPSHOT AGE\tFPS GUARD\tTELNET")
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\tunreachable\t-\t-\t-\t-\t-\n", r.Agent)
+			continue
+		}
+		st := r.Status
+		fps, players, age := "-", "-", "-"
+		if st.Snapshot != nil {
+			fps = fmt.Sprintf("%.1f", st.Snapshot.FPS)
+			players = fmt.Sprint(st.Snapshot.Players)
+			age = formatAge(time.Duration(st.Snapshot.AgeSeconds * float64(time.Second)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", st.Instance, r.Health.Status, fps, players, age, guardSummary(st.Policy), telnetSummary(st.Telnet, now))
+	}
+	tw.Flush()
+
+	for _, r := range results {
+		name := r.Agent
+		if r.Status != nil {
+			name = r.Status.Instance
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "\n%s: %s\n", name, r.Error)
+			continue
+		}
+		if len(r.Problems) > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, p := range r.Problems {
+			fmt.Fprintf(w, "%s: %s\n", name, p)
+		}
+		if len(r.Recent) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s: recent actions\n", name)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, ev := range r.Recent {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", eventTime(ev).Local().Format("2006-01-02 15:04:05"), ev.Status, ev.ActionType, firstNonEmpty(ev.Policy, ev.Caller, "-"), eventDetail(ev))
+		}
+		tw.Flush()
+	}
+}
+
+func guardSummary(p client.PolicyStatus) string {
+	g := p.FPSGuard
+	if g == nil {
+		return "disabled"
+	}
+	s := "normal"
+	if g.Throttled {
+		s = fmt.Sprintf("throttled %d/%d", g.Step+1, g.Steps)
+	}
+	for _, name := range p.Paused {
+		if name == "fps_guard" {
+			s += " (paused)"
+		}
+	}
+	return s
+}
+
+func telnetSummary(t *client.TelnetStatus, now time.Time) string {
+	switch {
+	case t == nil:
+		return "-"
+	case t.BreakerOpen:
+		return fmt.Sprintf("breaker open (%s left)", formatAge(t.BreakerUntil.Sub(now)))
+	case !t.Connected:
+		return "disconnected"
+	case !t.Authenticated:
+		return "connected, not authenticated"
+	default:
+		return "ok"
+	}
+}
+
+// eventTime is when the event last changed state.
+func eventTime(ev client.AuditEvent) time.Time {
+	for _, t := range []time.Time{ev.DoneAt, ev.SentAt} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return ev.QueuedAt
+}
+
+func eventDetail(ev client.AuditEvent) string {
+	parts := []string{}
+	if ev.Reason != "" {
+		parts = append(parts, ev.Reason)
+	}
+	if len(ev.Commands) > 0 {
+		parts = append(parts, strings.Join(ev.Commands, "; "))
+	}
+	if ev.Error != "" {
+		parts = append(parts, "error: "+ev.Error)
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/api"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// testAgent serves the agent API with one snapshot parsed age ago.
+func testAgent(t *testing.T, name string, age time.Duration) (string, *state.AuditRing) {
+	t.Helper()
+	return testAgentToken(t, name, age, "read-secret")
+}
+
+// testAgentToken is testAgent with the given read token.
+func testAgentToken(t *testing.T, name string, age time.Duration, token string) (string, *state.AuditRing) {
+	t.Helper()
+	snaps := state.NewSnapshotStore()
+	now := time.Now()
+	snaps.Update(state.Snapshot{Timestamp: now.Add(-age), ParsedAt: now.Add(-age), FPS: 37.5, Players: 4, EntitiesActive: -1})
+	ring := state.NewAuditRing(50)
+	srv := api.NewServer("127.0.0.1:0", api.Deps{
+		Instance:  name,
+		Snapshots: snaps,
+		Audit:     ring,
+		ReadToken: token,
+		StartedAt: now.Add(-time.Hour),
+	})
+	ring.AddSink("events", srv.AuditSink(), state.AuditFilter{})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts.URL, ring
+}
+
+func runCtl(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStatus(t *testing.T) {
+	addr, ring := testAgent(t, "main", 2*time.Second)
+	ring.Append(state.AuditEvent{ActionID: "a1", ActionType: "SetGamePref", Status: "success", Policy: "fps_guard", Reason: "fps low", QueuedAt: time.Now()})
+	ring.Append(state.AuditEvent{ActionID: "x", ActionType: "APIAccess", Status: "denied", QueuedAt: time.Now()})
+	t.Setenv("MG7D_CTL_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Setenv("MG7D_TOKEN", "read-secret")
+
+	code, out, errOut := runCtl(t, "status", "-addr", addr)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s%s", code, out, errOut)
+	}
+	for _, want := range []string{"main", "37.5", "disabled", "fps low", "SetGamePref"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "APIAccess") {
+		t.Errorf("table lists API access denials:\n%s", out)
+	}
+
+	code, out, _ = runCtl(t, "status", "-addr", addr, "-o", "json")
+	var res []instanceStatus
+	if err := json.Unmarshal([]byte(out), &res); err != nil || code != exitOK {
+		t.Fatalf("json: %d %v\n%s", code, err, out)
+	}
+	if len(res) != 1 || !res[0].Healthy || res[0].Status.Snapshot.Players != 4 || len(res[0].Recent) != 1 {
+		t.Errorf("json: %+v", res)
+	}
+	if code, out, _ = runCtl(t, "status", "-addr", addr, "-o", "yaml", "-actions", "0"); code != exitOK || !strings.Contains(out, "instance: main") {
+		t.Errorf("yaml: %d\n%s", code, out)
+	}
+
+	t.Setenv("MG7D_TOKEN", "wrong")
+	if code, _, _ := runCtl(t, "status", "-addr", addr); code != exitAgent {
+		t.Errorf("bad token: exit %d", code)
+	}
+	if code, _, _ := runCtl(t, "status", "-addr", addr, "-o", "xml"); code != exitUsage {
+		t.Errorf("bad format: exit %d", code)
+	}
+}
+
+func TestStatusConfigAndUnhealthy(t *testing.T) {
+	ok, _ := testAgent(t, "main", time.Second)
+	stale, _ := testAgent(t, "event", 20*time.Minute)
+	cfg := filepath.Join(t.TempDir(), "ctl.yaml")
+	body := "agents:\n" +
+		"  - {name: main, addr: " + ok + ", token_env: TEST_MAIN_TOKEN}\n" +
+		"  - {name: event, addr: " + stale + ", token: read-secret}\n"
+	if err := os.WriteFile(cfg, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MG7D_TOKEN", "")
+	t.Setenv("MG7D_ADDR", "")
+	t.Setenv("TEST_MAIN_TOKEN", "read-secret")
+
+	if code, out, errOut := runCtl(t, "status", "-ctl-config", cfg, "-agent", "main"); code != exitOK {
+		t.Errorf("main only: exit %d\n%s%s", code, out, errOut)
+	}
+	code, out, _ := runCtl(t, "status", "-ctl-config", cfg)
+	if code != exitFailed || !strings.Contains(out, "event: parser fail") {
+		t.Errorf("stale snapshot: exit %d\n%s", code, out)
+	}
+	if code, _, _ := runCtl(t, "status", "-ctl-config", cfg, "-agent", "nope"); code != exitUsage {
+		t.Errorf("unknown agent: exit %d", code)
+	}
+}
+
+// TestStatusGlobalTokenFallback checks that MG7D_TOKEN doesn't override the tokens of
+// agents in the ctl config and isn't sent to them; it only fills in for agents without one.
+func TestStatusGlobalTokenFallback(t *testing.T) {
+	main, _ := testAgentToken(t, "main", time.Second, "main-secret")
+	event, _ := testAgentToken(t, "event", time.Second, "event-secret")
+	cfg := filepath.Join(t.TempDir(), "ctl.yaml")
+	body := "agents:\n" +
+		"  - {name: main, addr: " + main + ", token: main-secret}\n" +
+		"  - {name: event, addr: " + event + "}\n"
+	if err := os.WriteFile(cfg, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MG7D_ADDR", "")
+	t.Setenv("MG7D_TOKEN", "event-secret")
+
+	if code, out, errOut := runCtl(t, "status", "-ctl-config", cfg); code != exitOK {
+		t.Errorf("exit %d\n%s%s", code, out, errOut)
+	}
+	if code, _, _ := runCtl(t, "status", "-ctl-config", cfg, "-token", "main-secret", "-agent", "event"); code != exitAgent {
+		t.Errorf("event with main's token: exit %d, want %d", code, exitAgent)
+	}
+}
@@ -9,22 +9,28 @@ type Action interface {
 	InstanceName() string
 	Reason() string
 	Type() string
+	Policy() string
+	Caller() string
 }
 
 // Base holds common action fields.
 type Base struct {
-	ActionID      string
-	ActionTime    time.Time
-	Instance      string
-	ReasonText    string
-	ActionType    string
+	ActionID   string
+	ActionTime time.Time
+	Instance   string
+	ReasonText string
+	ActionType string
+	PolicyName string // emitting policy; empty for manual actions
+	CallerID   string // API caller of a manual action (scope@address); empty for policy actions
 }
 
-func (b Base) ID() string              { return b.ActionID }
-func (b Base) Timestamp() time.Time    { return b.ActionTime }
-func (b Base) InstanceName() string     { return b.Instance }
-func (b Base) Reason() string          { return b.ReasonText }
-func (b Base) Type() string            { return b.ActionType }
+func (b Base) ID() string           { return b.ActionID }
+func (b Base) Timestamp() time.Time { return b.ActionTime }
+func (b Base) InstanceName() string { return b.Instance }
+func (b Base) Reason() string       { return b.ReasonText }
+func (b Base) Type() string         { return b.ActionType }
+func (b Base) Policy() string       { return b.PolicyName }
+func (b Base) Caller() string       { return b.CallerID }
 
 // SetGamePref sets a game preference.
 type SetGamePref struct {
@@ -3,6 +3,7 @@ package actions
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,15 +13,22 @@ import (
 
 // Applier applies actions via the telnet client. Bounded queue; drops on overload with audit.
 type Applier struct {
-	client    *telnet.Client
-	audit     *state.AuditRing
-	baseline  map[string]string
+	client     *telnet.Client
+	audit      *state.AuditRing
+	baseline   map[string]string
+	current    map[string]string // last value applied per pref; seeded from the baseline
 	baselineMu sync.RWMutex
-	queue     chan Action
-	queueSize int
-	mu        sync.Mutex
-	running   bool
-	cancel    context.CancelFunc
+	queue      chan queuedAction
+	queueSize  int
+	mu         sync.Mutex
+	running    bool
+	cancel     context.CancelFunc
+}
+
+type queuedAction struct {
+	action   Action
+	queuedAt time.Time
+	done     func(error) // may be nil
 }
 
 // NewApplier creates an applier with a bounded queue.
@@ -32,36 +40,52 @@ func NewApplier(client *telnet.Client, audit *state.AuditRing, queueSize int) *A
 		client:    client,
 		audit:     audit,
 		baseline:  make(map[string]string),
-		queue:     make(chan Action, queueSize),
+		current:   make(map[string]string),
+		queue:     make(chan queuedAction, queueSize),
 		queueSize: queueSize,
 	}
 }
 
-// SetBaseline sets the baseline prefs for RestoreBaseline actions.
+// SetBaseline sets the baseline prefs for RestoreBaseline actions. Prefs the applier
+// has not changed yet are assumed to be at their baseline value.
 func (a *Applier) SetBaseline(m map[string]string) {
 	a.baselineMu.Lock()
 	defer a.baselineMu.Unlock()
 	a.baseline = make(map[string]string)
 	for k, v := range m {
 		a.baseline[k] = v
+		if _, ok := a.current[k]; !ok {
+			a.current[k] = v
+		}
 	}
 }
 
+// QueueLen returns the number of actions waiting to be applied.
+func (a *Applier) QueueLen() int { return len(a.queue) }
+
+// QueueCap returns the action queue capacity.
+func (a *Applier) QueueCap() int { return a.queueSize }
+
 // Enqueue adds an action. If queue is full, records audit and returns error.
 func (a *Applier) Enqueue(ctx context.Context, action Action) error {
-	ev := state.AuditEvent{
-		ActionID:   action.ID(),
-		ActionType: action.Type(),
-		Status:     "queued",
-		QueuedAt:   time.Now(),
-	}
+	return a.EnqueueFunc(ctx, action, nil)
+}
+
+// EnqueueFunc is Enqueue with done called once the action has been applied: with nil on
+// success, or the telnet error. done is not called for a dropped action.
+func (a *Applier) EnqueueFunc(ctx context.Context, action Action, done func(error)) error {
+	cmds, changes, _ := a.plan(action)
+	ev := describe(action, cmds, changes)
+	ev.Status = "queued"
+	ev.QueuedAt = time.Now()
 	a.audit.Append(ev)
 	select {
-	case a.queue <- action:
+	case a.queue <- queuedAction{action: action, queuedAt: ev.QueuedAt, done: done}:
 		return nil
 	default:
 		ev.Status = "dropped"
 		ev.Error = "queue full"
+		ev.DoneAt = time.Now()
 		a.audit.Append(ev)
 		return fmt.Errorf("applier: queue full")
 	}
@@ -87,56 +111,122 @@ func (a *Applier) Run(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case action, ok := <-a.queue:
+		case qa, ok := <-a.queue:
 			if !ok {
 				return
 			}
-			a.applyOne(ctx, action)
+			a.applyOne(ctx, qa)
 		}
 	}
 }
 
-func (a *Applier) applyOne(ctx context.Context, action Action) {
-	sentAt := time.Now()
-	ev := state.AuditEvent{
-		ActionID:   action.ID(),
-		ActionType: action.Type(),
-		Status:     "sent",
-		SentAt:     sentAt,
-	}
-	var err error
+// plan returns the telnet commands an action sends and the prefs it changes.
+func (a *Applier) plan(action Action) ([]telnet.Command, []state.PrefChange, error) {
+	a.baselineMu.RLock()
+	defer a.baselineMu.RUnlock()
 	switch act := action.(type) {
 	case *SetGamePref:
-		err = a.client.Send(ctx, telnet.SetGamePref(act.Pref, act.Value))
+		return []telnet.Command{telnet.SetGamePref(act.Pref, act.Value)},
+			[]state.PrefChange{{Pref: act.Pref, OldValue: a.current[act.Pref], NewValue: act.Value}}, nil
 	case *Say:
-		err = a.client.Send(ctx, telnet.Say(act.Message))
+		return []telnet.Command{telnet.Say(act.Message)}, nil, nil
 	case *RestoreBaseline:
-		err = a.applyRestoreBaseline(ctx, act)
+		prefs := make([]string, 0, len(a.baseline))
+		for pref := range a.baseline {
+			prefs = append(prefs, pref)
+		}
+		sort.Strings(prefs)
+		var cmds []telnet.Command
+		var changes []state.PrefChange
+		for _, pref := range prefs {
+			val := a.baseline[pref]
+			cmds = append(cmds, telnet.SetGamePref(pref, val))
+			changes = append(changes, state.PrefChange{Pref: pref, OldValue: a.current[pref], NewValue: val})
+		}
+		return cmds, changes, nil
 	case *Noop:
-		err = nil
+		return nil, nil, nil
 	default:
-		err = fmt.Errorf("unknown action type: %T", action)
+		return nil, nil, fmt.Errorf("unknown action type: %T", action)
 	}
-	doneAt := time.Now()
-	ev.DoneAt = doneAt
+}
+
+// Preview returns the audit event the action would produce without queueing it: the
+// telnet commands it would send and the prefs it would change.
+func (a *Applier) Preview(action Action) (state.AuditEvent, error) {
+	cmds, changes, err := a.plan(action)
+	if err != nil {
+		return state.AuditEvent{}, fmt.Errorf("applier: %w", err)
+	}
+	return describe(action, cmds, changes), nil
+}
+
+// Simulate plans the action and records its pref changes as applied without sending
+// anything, so later actions see the values it would have set. Replay uses it in
+// place of Enqueue.
+func (a *Applier) Simulate(action Action) (state.AuditEvent, error) {
+	cmds, changes, err := a.plan(action)
+	if err != nil {
+		return state.AuditEvent{}, fmt.Errorf("applier: %w", err)
+	}
+	ev := describe(action, cmds, changes)
+	a.baselineMu.Lock()
+	for _, c := range ev.Changes {
+		a.current[c.Pref] = c.NewValue
+	}
+	a.baselineMu.Unlock()
+	return ev, nil
+}
+
+// describe returns an audit event carrying the action's description and the commands
+// and changes planned for it; the caller sets Status and timestamps.
+func describe(action Action, cmds []telnet.Command, changes []state.PrefChange) state.AuditEvent {
+	ev := state.AuditEvent{
+		ActionID:   action.ID(),
+		ActionType: action.Type(),
+		Instance:   action.InstanceName(),
+		Policy:     action.Policy(),
+		Caller:     action.Caller(),
+		Reason:     action.Reason(),
+	}
+	ev.Changes = changes
+	for _, c := range cmds {
+		ev.Commands = append(ev.Commands, c.Raw)
+	}
+	return ev
+}
+
+func (a *Applier) applyOne(ctx context.Context, qa queuedAction) {
+	// One plan for both the audit event and the commands sent, so the audit trail
+	// records exactly what went to telnet.
+	cmds, changes, err := a.plan(qa.action)
+	ev := describe(qa.action, cmds, changes)
+	ev.QueuedAt = qa.queuedAt
+	ev.SentAt = time.Now()
+	ev.Status = "sent"
+	a.audit.Append(ev)
+
+	if err == nil {
+		for _, cmd := range cmds {
+			if err = a.client.Send(ctx, cmd); err != nil {
+				break
+			}
+		}
+	}
+	ev.DoneAt = time.Now()
 	if err != nil {
 		ev.Status = "failure"
 		ev.Error = err.Error()
 	} else {
 		ev.Status = "success"
+		a.baselineMu.Lock()
+		for _, c := range ev.Changes {
+			a.current[c.Pref] = c.NewValue
+		}
+		a.baselineMu.Unlock()
 	}
 	a.audit.Append(ev)
-}
-
-// applyRestoreBaseline sends setpref for each baseline pref.
-func (a *Applier) applyRestoreBaseline(ctx context.Context, _ *RestoreBaseline) error {
-	a.baselineMu.RLock()
-	baseline := a.baseline
-	a.baselineMu.RUnlock()
-	for pref, val := range baseline {
-		if err := a.client.Send(ctx, telnet.SetGamePref(pref, val)); err != nil {
-			return err
-		}
+	if qa.done != nil {
+		qa.done(err)
 	}
-	return nil
 }
@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// IDSource issues action IDs of the form prefix-boot-n, e.g. "act-sk2x1c9e4b-7". boot
+// is the creation time (base 36) plus random bits, so IDs stay unique across restarts
+// and do not collide with those in a restored audit log.
+type IDSource struct {
+	prefix string
+	boot   string
+	seq    atomic.Uint64
+}
+
+// NewIDSource creates an ID source; create one per process (or per engine).
+func NewIDSource(prefix string) *IDSource {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		b = []byte{byte(time.Now().UnixNano() >> 8), byte(time.Now().UnixNano())}
+	}
+	return &IDSource{
+		prefix: prefix,
+		boot:   strconv.FormatInt(time.Now().Unix(), 36) + hex.EncodeToString(b),
+	}
+}
+
+// Next returns a new ID.
+func (s *IDSource) Next() string {
+	return fmt.Sprintf("%s-%s-%d", s.prefix, s.boot, s.seq.Add(1))
+}
@@ -0,0 +1,186 @@
+// Package analysis derives trend signals (regression slopes, EWMA, leak and decline
+// flags) from the snapshot history for metrics and policies.
+package analysis
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// Options configures the analyzer. Zero values use the defaults noted per field.
+type Options struct {
+	Window            time.Duration // regression window; default 1h
+	MinSamples        int           // samples needed in the window for a slope; default 10
+	MinR2             float64       // fit quality needed to flag a trend; default 0.6
+	EWMAAlpha         float64       // weight of the newest sample, 0 < alpha <= 1; default 0.2
+	LeakMBPerHour     float64       // heap or RSS growth that flags a leak; default 200
+	FPSDeclinePerHour float64       // FPS loss per hour that flags a decline; default 10
+}
+
+// OptionsFrom converts the analysis config section to Options.
+func OptionsFrom(c config.Analysis) Options {
+	return Options{
+		Window:            time.Duration(c.WindowMinutes * float64(time.Minute)),
+		MinSamples:        c.MinSamples,
+		MinR2:             c.MinR2,
+		EWMAAlpha:         c.EWMAAlpha,
+		LeakMBPerHour:     c.LeakMBPerHour,
+		FPSDeclinePerHour: c.FPSDeclinePerHour,
+	}
+}
+
+// Trend is the derived view of one snapshot field.
+type Trend struct {
+	SlopePerHour float64 `json:"slope_per_hour"` // least-squares slope over the window
+	R2           float64 `json:"r2"`             // coefficient of determination of the fit
+	EWMA         float64 `json:"ewma"`
+	Samples      int     `json:"samples"`
+	Span         float64 `json:"span_seconds"` // time covered by the window's samples
+	Valid        bool    `json:"valid"`        // enough samples for a slope
+}
+
+// Signals is the analyzer output after one snapshot.
+type Signals struct {
+	At     time.Time             `json:"at"`
+	Trends map[state.Field]Trend `json:"trends"`
+	// MemoryLeak is set when heap or RSS grows by at least LeakMBPerHour with a good
+	// linear fit over at least half the window.
+	MemoryLeak bool `json:"memory_leak"`
+	// FPSDecline is set when FPS falls by at least FPSDeclinePerHour under the same
+	// conditions.
+	FPSDecline bool `json:"fps_decline"`
+}
+
+// Trend returns the trend of f; ok is false if there is none yet.
+func (s Signals) Trend(f state.Field) (Trend, bool) {
+	t, ok := s.Trends[f]
+	return t, ok && t.Valid
+}
+
+// Analyzer computes Signals from a shared History. Update is called once per
+// snapshot after it has been added to the history.
+type Analyzer struct {
+	opts    Options
+	history *state.History
+	mu      sync.Mutex
+	ewma    map[state.Field]float64
+	last    Signals
+}
+
+// New creates an analyzer over history. Unset options take the same defaults as the
+// analysis config section; config.Validate rejects the other values replaced here.
+func New(history *state.History, opts Options) *Analyzer {
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.MinSamples < 2 {
+		opts.MinSamples = 10
+	}
+	if opts.MinR2 <= 0 {
+		opts.MinR2 = 0.6
+	}
+	if opts.EWMAAlpha <= 0 || opts.EWMAAlpha > 1 {
+		opts.EWMAAlpha = 0.2
+	}
+	if opts.LeakMBPerHour <= 0 {
+		opts.LeakMBPerHour = 200
+	}
+	if opts.FPSDeclinePerHour <= 0 {
+		opts.FPSDeclinePerHour = 10
+	}
+	return &Analyzer{opts: opts, history: history, ewma: make(map[state.Field]float64)}
+}
+
+// Update folds snap into the EWMAs and recomputes slopes over the window ending at the
+// snapshot's timestamp.
+func (a *Analyzer) Update(snap state.Snapshot) Signals {
+	window := a.history.Window(snap.Timestamp, a.opts.Window)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sig := Signals{At: snap.Timestamp, Trends: make(map[state.Field]Trend, len(state.Fields))}
+	for _, f := range state.Fields {
+		t := regress(f, window)
+		if v, ok := f.Value(snap); ok {
+			if prev, seen := a.ewma[f]; seen {
+				a.ewma[f] = prev + a.opts.EWMAAlpha*(v-prev)
+			} else {
+				a.ewma[f] = v
+			}
+		}
+		t.EWMA = a.ewma[f]
+		t.Valid = t.Samples >= a.opts.MinSamples && t.Span > 0
+		sig.Trends[f] = t
+	}
+	sig.MemoryLeak = a.sustained(sig, state.FieldHeapMB, a.opts.LeakMBPerHour) ||
+		a.sustained(sig, state.FieldRSSMB, a.opts.LeakMBPerHour)
+	sig.FPSDecline = a.sustained(sig, state.FieldFPS, -a.opts.FPSDeclinePerHour)
+	a.last = sig
+	return sig
+}
+
+// Signals returns the result of the latest Update.
+func (a *Analyzer) Signals() Signals {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
+
+// sustained reports whether f's slope reaches threshold (a positive threshold means
+// growth, a negative one decline) with a good fit over at least half the window.
+func (a *Analyzer) sustained(sig Signals, f state.Field, threshold float64) bool {
+	t, ok := sig.Trend(f)
+	if !ok || t.R2 < a.opts.MinR2 || t.Span < a.opts.Window.Seconds()/2 {
+		return false
+	}
+	if threshold < 0 {
+		return t.SlopePerHour <= threshold
+	}
+	return t.SlopePerHour >= threshold
+}
+
+// regress fits value = a + b*t by least squares over the snapshots reporting f.
+func regress(f state.Field, snaps []state.Snapshot) Trend {
+	var n, sx, sy, sxx, sxy, syy float64
+	var t0, tEnd time.Time
+	for _, s := range snaps {
+		v, ok := f.Value(s)
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			t0 = s.Timestamp
+		}
+		tEnd = s.Timestamp
+		x := s.Timestamp.Sub(t0).Seconds()
+		n++
+		sx += x
+		sy += v
+		sxx += x * x
+		sxy += x * v
+		syy += v * v
+	}
+	tr := Trend{Samples: int(n), Span: tEnd.Sub(t0).Seconds()}
+	if n < 2 {
+		return tr
+	}
+	varX := n*sxx - sx*sx
+	if varX <= 0 {
+		return tr
+	}
+	cov := n*sxy - sx*sy
+	tr.SlopePerHour = cov / varX * 3600
+	if varY := n*syy - sy*sy; varY > 0 {
+		tr.R2 = cov * cov / (varX * varY)
+	} else {
+		tr.R2 = 1 // constant series: the flat line fits perfectly
+	}
+	if math.IsNaN(tr.R2) {
+		tr.R2 = 0
+	}
+	return tr
+}
@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func feed(a *Analyzer, h *state.History, n int, step time.Duration, snap func(i int) state.Snapshot) Signals {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var sig Signals
+	for i := 0; i < n; i++ {
+		s := snap(i)
+		s.Timestamp = base.Add(time.Duration(i) * step)
+		h.Add(s)
+		sig = a.Update(s)
+	}
+	return sig
+}
+
+func TestAnalyzer_MemoryLeak(t *testing.T) {
+	h := state.NewHistory(1000)
+	a := New(h, Options{Window: time.Hour})
+	// 120 samples over 60 minutes; heap grows 300 MB/h, RSS is flat, FPS steady.
+	sig := feed(a, h, 121, 30*time.Second, func(i int) state.Snapshot {
+		return state.Snapshot{FPS: 40 + float64(i%2), HeapMB: 1000 + float64(i)*2.5, RSSMB: 3000, EntitiesActive: -1}
+	})
+	heap, ok := sig.Trend(state.FieldHeapMB)
+	if !ok || math.Abs(heap.SlopePerHour-300) > 1e-6 || heap.R2 < 0.99 {
+		t.Fatalf("heap trend: %+v ok=%v", heap, ok)
+	}
+	if rss, _ := sig.Trend(state.FieldRSSMB); rss.SlopePerHour != 0 {
+		t.Errorf("flat rss slope = %v", rss.SlopePerHour)
+	}
+	if !sig.MemoryLeak || sig.FPSDecline {
+		t.Errorf("flags: leak=%v decline=%v", sig.MemoryLeak, sig.FPSDecline)
+	}
+	if heap.EWMA <= 1000 || heap.EWMA >= 1300 {
+		t.Errorf("heap EWMA = %v", heap.EWMA)
+	}
+	if _, ok := sig.Trend(state.FieldEntitiesActive); ok {
+		t.Error("unreported field should have no trend")
+	}
+	if got := a.Signals(); !got.MemoryLeak {
+		t.Error("Signals() should return the latest update")
+	}
+}
+
+func TestAnalyzer_FPSDeclineNeedsSustainedFit(t *testing.T) {
+	h := state.NewHistory(1000)
+	a := New(h, Options{Window: time.Hour})
+	// Too short a span: 10 minutes of decline is not sustained.
+	sig := feed(a, h, 20, 30*time.Second, func(i int) state.Snapshot {
+		return state.Snapshot{FPS: 60 - float64(i)}
+	})
+	if sig.FPSDecline {
+		t.Error("decline over 10 minutes should not be flagged with a 1h window")
+	}
+
+	h = state.NewHistory(1000)
+	a = New(h, Options{Window: time.Hour})
+	sig = feed(a, h, 121, 30*time.Second, func(i int) state.Snapshot {
+		return state.Snapshot{FPS: 60 - float64(i)*0.25} // -30 FPS/h
+	})
+	if fps, _ := sig.Trend(state.FieldFPS); !sig.FPSDecline || math.Abs(fps.SlopePerHour+30) > 1e-6 {
+		t.Errorf("expected decline at -30/h, got %+v decline=%v", fps, sig.FPSDecline)
+	}
+
+	h = state.NewHistory(1000)
+	a = New(h, Options{Window: time.Hour})
+	sig = feed(a, h, 121, 30*time.Second, func(i int) state.Snapshot {
+		return state.Snapshot{FPS: 40 + float64((i*37)%23)} // noisy, no trend
+	})
+	if sig.FPSDecline {
+		t.Error("noise should not be flagged as a decline")
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// maxRequestBody bounds admin request bodies.
+const maxRequestBody = 64 << 10
+
+// handlePolicyPause serves POST /api/v1/policies/{name}/pause and .../resume.
+func (s *Server) handlePolicyPause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.deps.Policy == nil {
+			writeError(w, http.StatusServiceUnavailable, "policy engine is not running")
+			return
+		}
+		var req PolicyRequest
+		if err := decodeBody(w, r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		name := r.PathValue("name")
+		set, typ := s.deps.Policy.Resume, "PolicyResume"
+		if paused {
+			set, typ = s.deps.Policy.Pause, "PolicyPause"
+		}
+		if err := set(name); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.appendAudit(state.AuditEvent{
+			ActionID:   s.ids.Next(),
+			ActionType: typ,
+			Status:     "success",
+			Policy:     name,
+			Caller:     callerFrom(r),
+			Reason:     manualReason(req.Reason),
+			DoneAt:     s.now(),
+		})
+		writeJSON(w, http.StatusOK, PolicyStateResponse{Policy: name, Paused: paused})
+	}
+}
+
+// handleRestoreBaseline serves POST /api/v1/actions/restore-baseline. Once the restore
+// has been applied it resets the policies, so the FPS guard does not consider the
+// server throttled any more. A failed restore leaves them as they are.
+func (s *Server) handleRestoreBaseline(w http.ResponseWriter, r *http.Request) {
+	var req RestoreBaselineRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a := actions.NewRestoreBaseline(s.ids.Next(), s.deps.Instance, manualReason(req.Reason))
+	a.CallerID = callerFrom(r)
+	s.submit(w, r, a, req.DryRun, func(err error) {
+		if err == nil && s.deps.Policy != nil {
+			s.deps.Policy.Reset(s.now())
+		}
+	})
+}
+
+// handleSetGamePref serves POST /api/v1/actions/set-game-pref.
+func (s *Server) handleSetGamePref(w http.ResponseWriter, r *http.Request) {
+	var req SetGamePrefRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Pref == "" || strings.IndexFunc(req.Pref, unicode.IsSpace) >= 0 || hasControl(req.Pref) {
+		writeError(w, http.StatusBadRequest, "pref must be a non-empty name without spaces")
+		return
+	}
+	if hasControl(req.Value) {
+		writeError(w, http.StatusBadRequest, "value must not contain control characters")
+		return
+	}
+	a := actions.NewSetGamePref(s.ids.Next(), s.deps.Instance, manualReason(req.Reason), req.Pref, req.Value)
+	a.CallerID = callerFrom(r)
+	s.submit(w, r, a, req.DryRun, nil)
+}
+
+// handleSay serves POST /api/v1/actions/say.
+func (s *Server) handleSay(w http.ResponseWriter, r *http.Request) {
+	var req SayRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" || hasControl(req.Message) {
+		writeError(w, http.StatusBadRequest, "message must be non-empty and must not contain control characters")
+		return
+	}
+	a := actions.NewSay(s.ids.Next(), s.deps.Instance, manualReason(req.Reason), req.Message)
+	a.CallerID = callerFrom(r)
+	s.submit(w, r, a, req.DryRun, nil)
+}
+
+// submit queues a manual action on the applier, or with dryRun records and returns
+// its preview, and writes the response. done (may be nil) is called after the applier
+// has applied the action.
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, a actions.Action, dryRun bool, done func(error)) {
+	if s.deps.Applier == nil {
+		writeError(w, http.StatusServiceUnavailable, "telnet is not configured")
+		return
+	}
+	ev, err := s.deps.Applier.Preview(a)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if dryRun {
+		ev.Status = "dry_run"
+		ev.DoneAt = s.now()
+		s.appendAudit(ev)
+		writeJSON(w, http.StatusOK, actionResponseOf(ev))
+		return
+	}
+	if err := s.deps.Applier.EnqueueFunc(r.Context(), a, done); err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("%s: %v", a.ID(), err))
+		return
+	}
+	ev.Status = "queued"
+	writeJSON(w, http.StatusAccepted, actionResponseOf(ev))
+}
+
+func (s *Server) appendAudit(ev state.AuditEvent) {
+	if ev.Instance == "" {
+		ev.Instance = s.deps.Instance
+	}
+	if s.deps.Audit != nil {
+		s.deps.Audit.Append(ev)
+	}
+}
+
+func manualReason(reason string) string {
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return "manual: api"
+	}
+	return "manual: " + reason
+}
+
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+// decodeBody decodes a JSON request body into v. An empty body leaves v unchanged.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
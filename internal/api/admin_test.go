@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+	"github.com/mg7d/mg7d/internal/telnet"
+)
+
+func adminServer(t *testing.T) (*Server, *state.AuditRing, *policy.Engine, *actions.Applier) {
+	t.Helper()
+	ring := state.NewAuditRing(100)
+	engine := policy.NewEngine("main", testInstance())
+	applier := actions.NewApplier(nil, ring, 4)
+	applier.SetBaseline(map[string]string{"A": "5", "B": "x"})
+	s := NewServer("127.0.0.1:0", Deps{
+		Instance:  "main",
+		Audit:     ring,
+		Policy:    engine,
+		Applier:   applier,
+		AuthToken: "admin-secret",
+		ReadToken: "read-secret",
+	})
+	return s, ring, engine, applier
+}
+
+func post(t *testing.T, s *Server, path, token, body string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if v != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, rec.Body)
+		}
+	}
+	return rec
+}
+
+func TestAdminPauseResume(t *testing.T) {
+	s, ring, engine, _ := adminServer(t)
+
+	if rec := post(t, s, "/api/v1/policies/fps_guard/pause", "read-secret", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("read token: %d", rec.Code)
+	}
+	var ps PolicyStateResponse
+	if rec := post(t, s, "/api/v1/policies/fps_guard/pause", "admin-secret", `{"reason":"event night"}`, &ps); rec.Code != http.StatusOK || !ps.Paused {
+		t.Fatalf("pause: %d %s", rec.Code, rec.Body)
+	}
+	for i := 0; i < 5; i++ {
+		if acts := engine.Evaluate(policy.Input{Snapshot: state.Snapshot{FPS: 5}}); len(acts) != 0 {
+			t.Fatalf("paused policy emitted %v", acts)
+		}
+	}
+	if rec := post(t, s, "/api/v1/policies/nope/pause", "admin-secret", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown policy: %d", rec.Code)
+	}
+	post(t, s, "/api/v1/policies/fps_guard/resume", "admin-secret", "", &ps)
+	if ps.Paused || len(engine.Paused()) != 0 {
+		t.Errorf("resume: %+v %v", ps, engine.Paused())
+	}
+
+	evs := ring.Query(state.AuditQuery{Policy: "fps_guard"})
+	if len(evs) != 2 || evs[0].ActionType != "PolicyPause" || evs[0].Caller != "admin@192.0.2.1" || evs[0].Reason != "manual: event night" || evs[1].ActionType != "PolicyResume" {
+		t.Errorf("audit: %+v", evs)
+	}
+}
+
+func TestAdminActions(t *testing.T) {
+	s, ring, _, applier := adminServer(t)
+
+	var resp ActionResponse
+	rec := post(t, s, "/api/v1/actions/restore-baseline", "admin-secret", `{"dry_run":true}`, &resp)
+	if rec.Code != http.StatusOK || resp.Status != "dry_run" || len(resp.Commands) != 2 || resp.Commands[0] != "setpref A 5" {
+		t.Fatalf("dry run: %d %+v", rec.Code, resp)
+	}
+	if applier.QueueLen() != 0 {
+		t.Errorf("dry run queued an action")
+	}
+
+	rec = post(t, s, "/api/v1/actions/set-game-pref", "admin-secret", `{"pref":"A","value":"3","reason":"test"}`, &resp)
+	if rec.Code != http.StatusAccepted || resp.Status != "queued" || resp.Changes[0].OldValue != "5" || applier.QueueLen() != 1 {
+		t.Fatalf("set-game-pref: %d %+v", rec.Code, resp)
+	}
+	queued := ring.Query(state.AuditQuery{ActionID: resp.ActionID, Status: "queued"})
+	if len(queued) != 1 || queued[0].Caller != "admin@192.0.2.1" || queued[0].Policy != "" {
+		t.Errorf("queued audit: %+v", queued)
+	}
+	if rec := post(t, s, "/api/v1/actions/say", "admin-secret", `{"message":"restart in 5\nkickall"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("control characters: %d", rec.Code)
+	}
+	if rec := post(t, s, "/api/v1/actions/set-game-pref", "admin-secret", `{"pref":"A B","value":"1"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("pref with space: %d", rec.Code)
+	}
+	if rec := post(t, s, "/api/v1/actions/say", "admin-secret", `{"message":"hi","extra":1}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: %d", rec.Code)
+	}
+	if n := len(ring.Query(state.AuditQuery{Status: "dry_run"})); n != 1 {
+		t.Errorf("dry_run events: %d", n)
+	}
+}
+
+func TestAdminActionIDsSurviveRestart(t *testing.T) {
+	var ids []string
+	for i := 0; i < 2; i++ { // a new server per agent start
+		s, _, _, _ := adminServer(t)
+		var resp ActionResponse
+		if rec := post(t, s, "/api/v1/actions/say", "admin-secret", `{"message":"hi","dry_run":true}`, &resp); rec.Code != http.StatusOK {
+			t.Fatalf("say: %d %s", rec.Code, rec.Body)
+		}
+		ids = append(ids, resp.ActionID)
+	}
+	if ids[0] == ids[1] || !strings.HasPrefix(ids[0], "api-") {
+		t.Errorf("action IDs after restart: %v", ids)
+	}
+}
+
+// TestAdminRestoreBaselineResetsOnSuccess checks that the policies are reset only after
+// the restore has been applied.
+func TestAdminRestoreBaselineResetsOnSuccess(t *testing.T) {
+	for _, reachable := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		cfg := telnet.Config{Host: "127.0.0.1", Port: 1}
+		if reachable {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Skip("no listener:", err)
+			}
+			defer ln.Close()
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					go func() { _, _ = io.Copy(io.Discard, conn) }()
+				}
+			}()
+			cfg.Port = ln.Addr().(*net.TCPAddr).Port
+		}
+		client := telnet.NewClient(cfg)
+		if reachable {
+			go client.Run(ctx)
+		} else {
+			stopped, stop := context.WithCancel(ctx)
+			stop()
+			client.Run(stopped)
+			client.Close() // every send fails
+		}
+		ring := state.NewAuditRing(100)
+		engine := policy.NewEngine("main", testInstance())
+		applier := actions.NewApplier(client, ring, 4)
+		applier.SetBaseline(map[string]string{"A": "5"})
+		go applier.Run(ctx)
+		s := NewServer("127.0.0.1:0", Deps{Instance: "main", Audit: ring, Policy: engine, Applier: applier, AuthToken: "admin-secret"})
+
+		for i := 0; i < 3; i++ {
+			engine.Evaluate(policy.Input{Snapshot: state.Snapshot{FPS: 5}})
+		}
+		if st, _ := engine.FPSGuardStatus(time.Now()); !st.Throttled {
+			t.Fatal("guard should be throttled")
+		}
+		var resp ActionResponse
+		if rec := post(t, s, "/api/v1/actions/restore-baseline", "admin-secret", "", &resp); rec.Code != http.StatusAccepted {
+			t.Fatalf("restore: %d %s", rec.Code, rec.Body)
+		}
+		want := "failure"
+		if reachable {
+			want = "success"
+		}
+		for len(ring.Query(state.AuditQuery{ActionID: resp.ActionID, Status: want})) == 0 {
+			if ctx.Err() != nil {
+				t.Fatalf("no %s event for %s", want, resp.ActionID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		// The reset follows the success event.
+		for reachable && ctx.Err() == nil {
+			if st, _ := engine.FPSGuardStatus(time.Now()); !st.Throttled {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if st, _ := engine.FPSGuardStatus(time.Now()); st.Throttled != !reachable {
+			t.Errorf("restore %s: throttled = %v", want, st.Throttled)
+		}
+	}
+}
+
+func TestAdminRequiresAdminToken(t *testing.T) {
+	s := NewServer("127.0.0.1:0", Deps{Policy: policy.NewEngine("main", testInstance())})
+	if rec := post(t, s, "/api/v1/policies/fps_guard/pause", "", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("admin endpoint without auth_token: %d", rec.Code)
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	ndjsonFlushEvery  = 100
+)
+
+// handleAudit serves GET /api/v1/audit: audit events from the in-memory ring and,
+// for events older than the ring, from the persistent audit log.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if s.deps.Audit == nil {
+		writeError(w, http.StatusNotFound, "audit is not available")
+		return
+	}
+	ndjson := r.URL.Query().Get("format") == "ndjson" || r.Header.Get("Accept") == "application/x-ndjson"
+	req, err := parseAuditRequest(r, ndjson)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	evs, next, err := s.queryAudit(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if ndjson {
+		writeNDJSON(w, evs)
+		return
+	}
+	page := AuditPage{Events: make([]AuditEvent, 0, len(evs)), NextCursor: next}
+	for _, ev := range evs {
+		page.Events = append(page.Events, AuditEventOf(ev))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// auditRequest is a parsed audit query. limit 0 means unlimited (NDJSON only).
+type auditRequest struct {
+	q     state.AuditQuery
+	limit int
+	desc  bool
+}
+
+func parseAuditRequest(r *http.Request, ndjson bool) (auditRequest, error) {
+	v := r.URL.Query()
+	req := auditRequest{q: state.AuditQuery{
+		ActionID:   v.Get("action_id"),
+		ActionType: v.Get("action_type"),
+		Status:     v.Get("status"),
+		Instance:   v.Get("instance"),
+		Policy:     v.Get("policy"),
+	}}
+	var err error
+	if req.q.Since, err = parseTimeParam(v.Get("since")); err != nil {
+		return req, fmt.Errorf("since: %w", err)
+	}
+	if req.q.Until, err = parseTimeParam(v.Get("until")); err != nil {
+		return req, fmt.Errorf("until: %w", err)
+	}
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		req.desc = true
+	default:
+		return req, fmt.Errorf("order must be asc or desc")
+	}
+	if !ndjson {
+		req.limit = defaultAuditLimit
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			return req, fmt.Errorf("limit must be between 1 and %d", maxAuditLimit)
+		}
+		req.limit = n
+	}
+	if s := v.Get("cursor"); s != "" {
+		seq, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || seq == 0 {
+			return req, fmt.Errorf("invalid cursor")
+		}
+		if req.desc {
+			req.q.BeforeSeq = seq
+		} else {
+			req.q.AfterSeq = seq
+		}
+	}
+	return req, nil
+}
+
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// queryAudit returns one page of matching events in the requested order and the
+// cursor for the next page ("" when this is the last page).
+func (s *Server) queryAudit(req auditRequest) ([]state.AuditEvent, string, error) {
+	evs := s.deps.Audit.Query(req.q)
+	oldest := s.deps.Audit.OldestSeq()
+	// Events older than the ring are only in the persistent log. Skip reading it when
+	// the page is already covered by the ring.
+	needLog := s.deps.AuditLog != nil && oldest != 1 && (oldest == 0 || req.q.AfterSeq+1 < oldest)
+	if needLog && req.desc && req.limit > 0 && len(evs) > req.limit {
+		needLog = false
+	}
+	if needLog {
+		q := req.q
+		if oldest > 0 && (q.BeforeSeq == 0 || oldest < q.BeforeSeq) {
+			q.BeforeSeq = oldest
+		}
+		older, err := s.deps.AuditLog.Query(q)
+		if err != nil {
+			return nil, "", fmt.Errorf("audit log: %w", err)
+		}
+		evs = append(older, evs...)
+		sort.SliceStable(evs, func(i, j int) bool { return evs[i].Seq < evs[j].Seq })
+	}
+	if req.desc {
+		for i, j := 0, len(evs)-1; i < j; i, j = i+1, j-1 {
+			evs[i], evs[j] = evs[j], evs[i]
+		}
+	}
+	if req.limit > 0 && len(evs) > req.limit {
+		evs = evs[:req.limit]
+		return evs, strconv.FormatUint(evs[len(evs)-1].Seq, 10), nil
+	}
+	return evs, "", nil
+}
+
+// writeNDJSON writes one JSON event per line, flushing periodically so large exports
+// stream instead of buffering.
+func writeNDJSON(w http.ResponseWriter, evs []state.AuditEvent) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for i, ev := range evs {
+		if err := enc.Encode(AuditEventOf(ev)); err != nil {
+			return
+		}
+		if flusher != nil && (i+1)%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+}
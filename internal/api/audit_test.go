@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func TestAuditPagination(t *testing.T) {
+	l, err := state.OpenAuditLog(state.AuditLogOptions{Path: filepath.Join(t.TempDir(), "audit.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ring := state.NewAuditRing(4)
+	ring.Persist(l, func(err error) { t.Error(err) })
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		status := "success"
+		if i%2 == 1 {
+			status = "failure"
+		}
+		ring.Append(state.AuditEvent{ActionID: "act", ActionType: "SetGamePref", Status: status, Instance: "main", QueuedAt: now})
+	}
+	s := NewServer("127.0.0.1:0", Deps{Audit: ring, AuditLog: l})
+
+	// Pages span the persistent log (seq 1-6) and the ring (seq 7-10).
+	var seqs []uint64
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		var page AuditPage
+		getJSON(t, s.Handler(), "/api/v1/audit?limit=3&cursor="+cursor, &page)
+		for _, ev := range page.Events {
+			seqs = append(seqs, ev.Seq)
+		}
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if len(seqs) != 10 || seqs[0] != 1 || seqs[9] != 10 {
+		t.Errorf("asc pages: %v", seqs)
+	}
+
+	var page AuditPage
+	getJSON(t, s.Handler(), "/api/v1/audit?status=failure&order=desc&limit=2", &page)
+	if len(page.Events) != 2 || page.Events[0].Seq != 10 || page.Events[1].Seq != 8 || page.NextCursor != "8" {
+		t.Errorf("desc page: %+v", page)
+	}
+	page = AuditPage{}
+	getJSON(t, s.Handler(), "/api/v1/audit?status=failure&order=desc&limit=5&cursor=8", &page)
+	if len(page.Events) != 3 || page.Events[2].Seq != 2 || page.NextCursor != "" {
+		t.Errorf("desc page 2: %+v", page)
+	}
+
+	for _, q := range []string{"limit=0", "cursor=x", "order=up", "since=yesterday"} {
+		if rec := getJSON(t, s.Handler(), "/api/v1/audit?"+q, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestAuditNDJSON(t *testing.T) {
+	ring := state.NewAuditRing(10)
+	for i := 0; i < 5; i++ {
+		ring.Append(state.AuditEvent{ActionID: "act", Status: "queued"})
+	}
+	s := NewServer("127.0.0.1:0", Deps{Audit: ring})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("content type %q", ct)
+	}
+	n := 0
+	for sc := bufio.NewScanner(rec.Body); sc.Scan(); n++ {
+		if !strings.HasPrefix(sc.Text(), `{"seq":`) {
+			t.Errorf("line %q", sc.Text())
+		}
+	}
+	if n != 5 {
+		t.Errorf("got %d lines", n)
+	}
+}
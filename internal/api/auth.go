@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// scope is the access level a bearer token grants. scopeAdmin includes scopeRead.
+type scope int
+
+const (
+	scopeNone scope = iota
+	scopeRead
+	scopeAdmin
+)
+
+func (s scope) String() string {
+	switch s {
+	case scopeRead:
+		return "read"
+	case scopeAdmin:
+		return "admin"
+	}
+	return "anonymous"
+}
+
+// maxDeniedPerMinute bounds the audit events written for rejected requests so a
+// scanner cannot flood the audit log; the excess is counted in the next event.
+const maxDeniedPerMinute = 60
+
+// authenticator checks bearer tokens. With no tokens configured every request is
+// granted admin scope, which keeps unauthenticated local setups working.
+type authenticator struct {
+	admin, read [sha256.Size]byte
+	hasAdmin    bool
+	hasRead     bool
+	exempt      map[string]bool
+
+	mu          sync.Mutex
+	windowStart time.Time
+	denied      int // audit events written in the current window
+	suppressed  int // denials not audited since the last audited one
+}
+
+func newAuthenticator(adminToken, readToken string, exempt []string) *authenticator {
+	a := &authenticator{exempt: make(map[string]bool)}
+	if adminToken != "" {
+		a.admin, a.hasAdmin = sha256.Sum256([]byte(adminToken)), true
+	}
+	if readToken != "" {
+		a.read, a.hasRead = sha256.Sum256([]byte(readToken)), true
+	}
+	for _, p := range exempt {
+		a.exempt[p] = true
+	}
+	return a
+}
+
+func (a *authenticator) enabled() bool {
+	return a.hasAdmin || a.hasRead
+}
+
+// scopeOf returns the scope granted by the request's token. Tokens are compared as
+// SHA-256 digests so the comparison is constant-time regardless of token length.
+func (a *authenticator) scopeOf(r *http.Request) scope {
+	if !a.enabled() {
+		return scopeAdmin
+	}
+	tok, ok := bearerToken(r)
+	if !ok {
+		return scopeNone
+	}
+	sum := sha256.Sum256([]byte(tok))
+	isAdmin := subtle.ConstantTimeCompare(sum[:], a.admin[:]) == 1 && a.hasAdmin
+	isRead := subtle.ConstantTimeCompare(sum[:], a.read[:]) == 1 && a.hasRead
+	switch {
+	case isAdmin:
+		return scopeAdmin
+	case isRead:
+		return scopeRead
+	}
+	return scopeNone
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(h[len(prefix):]), true
+}
+
+// allowDeniedAudit reports whether a denial at now may be audited, and how many
+// earlier denials were suppressed by the limit.
+func (a *authenticator) allowDeniedAudit(now time.Time) (bool, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.windowStart) >= time.Minute {
+		a.windowStart, a.denied = now, 0
+	}
+	if a.denied >= maxDeniedPerMinute {
+		a.suppressed++
+		return false, 0
+	}
+	a.denied++
+	n := a.suppressed
+	a.suppressed = 0
+	return true, n
+}
+
+// handle registers h for pattern, requiring scope need; scopeNone serves probes without
+// a token. Read routes whose path is in auth_exempt are served to GET and HEAD requests
+// without a token; admin routes never are.
+func (s *Server) handle(pattern string, need scope, h http.Handler) {
+	s.routes[pattern] = need
+	s.mux.Handle(pattern, s.requireScope(need, h))
+}
+
+func (s *Server) requireScope(need scope, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if need == scopeNone {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if need == scopeRead && (r.Method == http.MethodGet || r.Method == http.MethodHead) && s.auth.exempt[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+		got := s.auth.scopeOf(r)
+		switch {
+		case got == scopeNone:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mg7d"`)
+			s.deny(w, r, got, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		case got < need:
+			s.deny(w, r, got, http.StatusForbidden, fmt.Sprintf("%s scope required", need))
+			return
+		case need == scopeAdmin && !s.auth.hasAdmin:
+			s.deny(w, r, got, http.StatusForbidden, "admin endpoints require api.auth_token")
+			return
+		}
+		ctx := context.WithValue(r.Context(), callerKey{}, callerOf(r, got))
+		h.ServeHTTP(w, r.WithContext(context.WithValue(ctx, scopeKey{}, got)))
+	})
+}
+
+// deny writes an error response and records the rejected request in the audit ring.
+func (s *Server) deny(w http.ResponseWriter, r *http.Request, got scope, code int, msg string) {
+	writeError(w, code, msg)
+	if s.deps.Audit == nil {
+		return
+	}
+	now := s.now()
+	ok, suppressed := s.auth.allowDeniedAudit(now)
+	if !ok {
+		return
+	}
+	reason := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+	if suppressed > 0 {
+		reason += fmt.Sprintf(" (%d earlier denials not audited)", suppressed)
+	}
+	s.deps.Audit.Append(state.AuditEvent{
+		ActionType: "APIAccess",
+		Status:     "denied",
+		Error:      fmt.Sprintf("%d %s", code, msg),
+		Instance:   s.deps.Instance,
+		Caller:     callerOf(r, got),
+		Reason:     reason,
+		DoneAt:     now,
+	})
+}
+
+type (
+	callerKey struct{}
+	scopeKey  struct{}
+)
+
+// callerFrom returns the caller identity requireScope stored in the request context.
+func callerFrom(r *http.Request) string {
+	c, _ := r.Context().Value(callerKey{}).(string)
+	return c
+}
+
+// scopeFrom returns the scope requireScope granted the request; scopeNone on exempt
+// paths.
+func scopeFrom(r *http.Request) scope {
+	sc, _ := r.Context().Value(scopeKey{}).(scope)
+	return sc
+}
+
+// handleWhoami serves GET /api/v1/whoami: the scope the request's token grants.
+func (s *Server) handleWhoami(w http.ResponseWriter, r *http.Request) {
+	sc := scopeFrom(r)
+	writeJSON(w, http.StatusOK, WhoamiResponse{
+		Scope:       sc.String(),
+		Caller:      callerFrom(r),
+		AuthEnabled: s.auth.enabled(),
+		Admin:       sc == scopeAdmin && s.auth.hasAdmin,
+	})
+}
+
+// callerOf identifies the client as scope@address.
+func callerOf(r *http.Request, sc scope) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return sc.String() + "@" + host
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func TestAuthScopes(t *testing.T) {
+	ring := state.NewAuditRing(10)
+	s := NewServer("127.0.0.1:0", Deps{
+		Instance:   "main",
+		Audit:      ring,
+		Metrics:    http.NotFoundHandler(),
+		AuthToken:  "admin-secret",
+		ReadToken:  "read-secret",
+		AuthExempt: []string{"/healthz"},
+	})
+	do := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, c := range []struct {
+		path, token string
+		want        int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "", http.StatusOK}, // probes never need a token
+		{"/api/v1/status", "", http.StatusUnauthorized},
+		{"/api/v1/status", "wrong", http.StatusUnauthorized},
+		{"/api/v1/status", "read-secret", http.StatusOK},
+		{"/api/v1/status", "admin-secret", http.StatusOK},
+		{"/metrics", "", http.StatusUnauthorized},
+		{"/metrics", "read-secret", http.StatusNotFound}, // reached the handler
+	} {
+		if got := do(c.path, c.token); got != c.want {
+			t.Errorf("GET %s with %q: got %d, want %d", c.path, c.token, got, c.want)
+		}
+	}
+
+	evs := ring.Query(state.AuditQuery{Status: "denied"})
+	if len(evs) != 3 {
+		t.Fatalf("got %d denied events", len(evs))
+	}
+	if ev := evs[0]; ev.ActionType != "APIAccess" || ev.Caller != "anonymous@192.0.2.1" || ev.Reason != "GET /api/v1/status" || ev.Error == "" {
+		t.Errorf("denied event: %+v", ev)
+	}
+}
+
+// TestAuthExemptReadOnly checks that auth_exempt never opens admin routes or
+// non-GET requests, even when a config slips past validation.
+func TestAuthExemptReadOnly(t *testing.T) {
+	s := NewServer("127.0.0.1:0", Deps{
+		AuthToken:  "admin-secret",
+		AuthExempt: []string{"/api/v1/status", "/api/v1/actions/say"},
+	})
+	do := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(`{"message":"hi"}`)))
+		return rec.Code
+	}
+	if got := do(http.MethodGet, "/api/v1/status"); got != http.StatusOK {
+		t.Errorf("GET exempt read path: %d", got)
+	}
+	if got := do(http.MethodPost, "/api/v1/actions/say"); got != http.StatusUnauthorized {
+		t.Errorf("POST exempt admin path: %d, want 401", got)
+	}
+}
+
+func TestAuthForbiddenAndLimit(t *testing.T) {
+	ring := state.NewAuditRing(200)
+	s := NewServer("127.0.0.1:0", Deps{Audit: ring, AuthToken: "admin-secret", ReadToken: "read-secret"})
+	s.handle("POST /admin", scopeAdmin, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	post := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if got := post("read-secret"); got != http.StatusForbidden {
+		t.Errorf("read token on admin endpoint: %d", got)
+	}
+	if got := post("admin-secret"); got != http.StatusNoContent {
+		t.Errorf("admin token: %d", got)
+	}
+	if ev := ring.Query(state.AuditQuery{Status: "denied"}); len(ev) != 1 || ev[0].Caller != "read@192.0.2.1" {
+		t.Errorf("forbidden event: %+v", ev)
+	}
+
+	for i := 0; i < 2*maxDeniedPerMinute; i++ {
+		post("bad")
+	}
+	if n := len(ring.Query(state.AuditQuery{Status: "denied"})); n != maxDeniedPerMinute {
+		t.Errorf("audited %d denials, want %d", n, maxDeniedPerMinute)
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	s := NewServer("127.0.0.1:0", Deps{})
+	if rec := getJSON(t, s.Handler(), "/api/v1/status", nil); rec.Code != http.StatusOK {
+		t.Errorf("no tokens configured: %d", rec.Code)
+	}
+}
+
+func TestWhoami(t *testing.T) {
+	get := func(s *Server, token string) (WhoamiResponse, int) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		var w WhoamiResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &w)
+		return w, rec.Code
+	}
+
+	s := NewServer("127.0.0.1:0", Deps{AuthToken: "admin-secret", ReadToken: "read-secret"})
+	if _, code := get(s, ""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous: %d", code)
+	}
+	if w, _ := get(s, "read-secret"); w.Scope != "read" || w.Admin || !w.AuthEnabled || w.Caller != "read@192.0.2.1" {
+		t.Errorf("read: %+v", w)
+	}
+	if w, _ := get(s, "admin-secret"); w.Scope != "admin" || !w.Admin {
+		t.Errorf("admin: %+v", w)
+	}
+
+	readOnly := NewServer("127.0.0.1:0", Deps{ReadToken: "read-secret"})
+	if w, _ := get(readOnly, "read-secret"); w.Admin {
+		t.Errorf("admin without auth_token: %+v", w)
+	}
+	open := NewServer("127.0.0.1:0", Deps{})
+	if w, _ := get(open, ""); w.AuthEnabled || w.Admin || w.Scope != "admin" {
+		t.Errorf("auth disabled, admin endpoints off: %+v", w)
+	}
+}
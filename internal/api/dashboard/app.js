@@ -0,0 +1,351 @@
+// mg7d dashboard: reads the JSON API with the token the user enters and follows
+// /api/v1/events for live updates. No external dependencies.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const state = { token: sessionStorage.getItem("mg7d-token") || "", admin: false, history: null, abort: null };
+
+// --- API ---------------------------------------------------------------------
+
+async function api(path, opts = {}) {
+  const headers = { ...(opts.headers || {}) };
+  if (state.token) headers.Authorization = "Bearer " + state.token;
+  if (opts.body) headers["Content-Type"] = "application/json";
+  const res = await fetch(path, { ...opts, headers });
+  const text = await res.text();
+  let body = null;
+  try { body = text ? JSON.parse(text) : null; } catch (_) { body = { error: text }; }
+  if (!res.ok) {
+    const err = new Error((body && body.error) || res.statusText);
+    err.status = res.status;
+    err.body = body;
+    throw err;
+  }
+  return body;
+}
+
+function showError(err) {
+  const el = $("error");
+  if (!err) { el.hidden = true; return; }
+  el.textContent = err.status === 401 ? "Enter an API token to connect." : String(err.message || err);
+  el.hidden = false;
+}
+
+// --- formatting --------------------------------------------------------------
+
+const fmtTime = (t) => new Date(t).toLocaleTimeString();
+const isZero = (t) => !t || t.startsWith("0001-");
+const num = (v, d = 0) => (v === null || v === undefined ? "–" : Number(v).toFixed(d));
+
+function setKV(dl, pairs) {
+  dl.replaceChildren();
+  for (const [k, v] of pairs) {
+    const dt = document.createElement("dt");
+    dt.textContent = k;
+    const dd = document.createElement("dd");
+    dd.textContent = v;
+    dl.append(dt, dd);
+  }
+}
+
+// --- charts ------------------------------------------------------------------
+
+const charts = {
+  "chart-fps": [["fps", "#58a6ff"]],
+  "chart-players": [["players", "#3fb950"]],
+  "chart-entities": [["entities", "#d29922"], ["zombies", "#f85149"]],
+  "chart-mem": [["heap_mb", "#a371f7"], ["rss_mb", "#8a94a0"]],
+};
+
+function drawChart(canvas, timestamps, lines) {
+  const dpr = window.devicePixelRatio || 1;
+  const w = canvas.clientWidth, h = canvas.clientHeight;
+  canvas.width = w * dpr;
+  canvas.height = h * dpr;
+  const ctx = canvas.getContext("2d");
+  ctx.scale(dpr, dpr);
+  ctx.clearRect(0, 0, w, h);
+  ctx.font = "11px system-ui, sans-serif";
+  ctx.fillStyle = "#8a94a0";
+
+  const xs = timestamps.map((t) => new Date(t).getTime());
+  let lo = Infinity, hi = -Infinity;
+  for (const [, , values] of lines) for (const v of values) if (v !== null) { lo = Math.min(lo, v); hi = Math.max(hi, v); }
+  if (xs.length < 2 || lo === Infinity) {
+    ctx.fillText("no data", w / 2 - 20, h / 2);
+    return;
+  }
+  if (hi === lo) { hi += 1; lo = Math.max(0, lo - 1); }
+  const pad = { l: 44, r: 8, t: 8, b: 18 };
+  const x0 = xs[0], x1 = xs[xs.length - 1];
+  const px = (x) => pad.l + ((x - x0) / (x1 - x0 || 1)) * (w - pad.l - pad.r);
+  const py = (v) => h - pad.b - ((v - lo) / (hi - lo)) * (h - pad.t - pad.b);
+
+  ctx.strokeStyle = "#30363d";
+  ctx.lineWidth = 1;
+  for (let i = 0; i <= 4; i++) {
+    const v = lo + ((hi - lo) * i) / 4;
+    const y = py(v);
+    ctx.beginPath(); ctx.moveTo(pad.l, y); ctx.lineTo(w - pad.r, y); ctx.stroke();
+    ctx.fillText(v >= 100 ? v.toFixed(0) : v.toFixed(1), 4, y + 4);
+  }
+  ctx.fillText(fmtTime(x0), pad.l, h - 4);
+  const last = fmtTime(x1);
+  ctx.fillText(last, w - pad.r - ctx.measureText(last).width, h - 4);
+
+  let lx = pad.l + 4;
+  for (const [name, color, values] of lines) {
+    ctx.strokeStyle = color;
+    ctx.lineWidth = 1.5;
+    ctx.beginPath();
+    let pen = false;
+    values.forEach((v, i) => {
+      if (v === null) { pen = false; return; }
+      if (pen) ctx.lineTo(px(xs[i]), py(v)); else ctx.moveTo(px(xs[i]), py(v));
+      pen = true;
+    });
+    ctx.stroke();
+    ctx.fillStyle = color;
+    ctx.fillText(name, lx, pad.t + 10);
+    lx += ctx.measureText(name).width + 10;
+  }
+}
+
+function drawCharts() {
+  const hist = state.history;
+  if (!hist) return;
+  for (const [id, fields] of Object.entries(charts)) {
+    drawChart($(id), hist.timestamps, fields.map(([f, color]) => [f, color, hist.series[f] || []]));
+  }
+}
+
+async function loadHistory() {
+  state.history = await api("/api/v1/history?window=" + $("window").value + "&fields=fps,players,entities,zombies,heap_mb,rss_mb&max_points=600");
+  drawCharts();
+}
+
+// appendSnapshot adds a live snapshot to the charts, dropping points older than
+// the window.
+function appendSnapshot(snap) {
+  const hist = state.history;
+  if (!hist) return;
+  hist.timestamps.push(snap.timestamp);
+  for (const f of Object.keys(hist.series)) hist.series[f].push(snap[f] === undefined ? null : snap[f]);
+  const cutoff = new Date(snap.timestamp).getTime() - parseDuration($("window").value);
+  while (hist.timestamps.length && new Date(hist.timestamps[0]).getTime() < cutoff) {
+    hist.timestamps.shift();
+    for (const f of Object.keys(hist.series)) hist.series[f].shift();
+  }
+  drawCharts();
+}
+
+const parseDuration = (s) => parseInt(s, 10) * 3600 * 1000;
+
+// --- panels ------------------------------------------------------------------
+
+function renderSnapshot(snap) {
+  if (!snap) return;
+  $("cur-fps").textContent = num(snap.fps, 1);
+  $("cur-players").textContent = num(snap.players);
+  $("cur-entities").textContent = num(snap.entities) + " / " + num(snap.zombies);
+  $("cur-mem").textContent = num(snap.heap_mb) + " / " + num(snap.rss_mb);
+}
+
+function renderPolicy(policy) {
+  const g = policy && policy.fps_guard;
+  const paused = policy && policy.paused && policy.paused.includes("fps_guard");
+  if (!g) { setKV($("guard"), [["state", "disabled"]]); return; }
+  setKV($("guard"), [
+    ["state", (g.throttled ? "throttled" : "normal") + (paused ? " (paused)" : "")],
+    ["step", g.throttled ? g.step + 1 + " of " + g.steps : "–"],
+    ["profile", g.profile],
+    ["last action", isZero(g.last_action) ? "never" : fmtTime(g.last_action)],
+    ["cooldown", num(g.cooldown_remaining_seconds) + "s"],
+    ["restore in", isZero(g.restore_timer_started) ? "–" : num(g.restore_remaining_seconds) + "s"],
+  ]);
+}
+
+function renderTelnet(t) {
+  if (!t) { setKV($("telnet"), [["state", "not configured"]]); return; }
+  setKV($("telnet"), [
+    ["address", t.addr],
+    ["connected", t.connected ? "yes, since " + fmtTime(t.connected_at) : "no"],
+    ["authenticated", t.authenticated ? "yes" : "no"],
+    ["breaker", t.breaker_open ? "open until " + fmtTime(t.breaker_until) : "closed"],
+    ["failures", String(t.consecutive_failures)],
+    ["last error", t.last_error ? t.last_error + " (" + fmtTime(t.last_error_at) + ")" : "–"],
+  ]);
+}
+
+function renderHealth(h) {
+  const el = $("health");
+  el.textContent = h.status;
+  el.className = "big status-" + h.status;
+  const ul = $("health-detail");
+  ul.replaceChildren();
+  for (const [name, c] of Object.entries(h.components)) {
+    const li = document.createElement("li");
+    li.className = "status-" + c.status;
+    li.textContent = name + ": " + c.message;
+    ul.append(li);
+  }
+}
+
+function auditItem(ev) {
+  const li = document.createElement("li");
+  li.className = ev.status;
+  const head = document.createElement("div");
+  head.textContent = ev.status + " · " + ev.action_type + (ev.policy ? " · " + ev.policy : "") + (ev.caller ? " · " + ev.caller : "");
+  const text = document.createElement("div");
+  text.className = "text";
+  text.textContent = [ev.reason, (ev.commands || []).join("; "), ev.error].filter(Boolean).join(" — ");
+  const meta = document.createElement("div");
+  meta.className = "meta";
+  meta.textContent = "#" + ev.seq + " " + ev.action_id + " · " + fmtTime(ev.done_at && !isZero(ev.done_at) ? ev.done_at : ev.sent_at && !isZero(ev.sent_at) ? ev.sent_at : ev.queued_at);
+  li.append(head, text, meta);
+  return li;
+}
+
+function prependAudit(ev) {
+  const ol = $("audit");
+  ol.prepend(auditItem(ev));
+  while (ol.children.length > 100) ol.lastChild.remove();
+}
+
+async function refresh() {
+  const [st, health] = await Promise.all([api("/api/v1/status"), api("/api/v1/health").catch((e) => e.body)]);
+  $("instance").textContent = st.instance;
+  renderSnapshot(st.snapshot);
+  renderPolicy(st.policy);
+  renderTelnet(st.telnet);
+  if (health) renderHealth(health);
+}
+
+async function loadAudit() {
+  const page = await api("/api/v1/audit?order=desc&limit=50");
+  $("audit").replaceChildren(...page.events.map(auditItem));
+}
+
+// --- live events -------------------------------------------------------------
+
+// follow reads the SSE stream with fetch (EventSource cannot send the token header)
+// and reconnects with backoff.
+async function follow() {
+  let delay = 1000;
+  for (;;) {
+    const ctrl = new AbortController();
+    state.abort = ctrl;
+    try {
+      const res = await fetch("/api/v1/events", {
+        headers: state.token ? { Authorization: "Bearer " + state.token } : {},
+        signal: ctrl.signal,
+      });
+      if (!res.ok) throw new Error("events: " + res.status);
+      $("live").textContent = "live";
+      $("live").classList.add("on");
+      delay = 1000;
+      const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buf = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buf += value;
+        let i;
+        while ((i = buf.indexOf("\n\n")) >= 0) {
+          handleEvent(buf.slice(0, i));
+          buf = buf.slice(i + 2);
+        }
+      }
+    } catch (err) {
+      if (ctrl.signal.aborted) return;
+    }
+    $("live").textContent = "offline";
+    $("live").classList.remove("on");
+    await new Promise((r) => setTimeout(r, delay));
+    delay = Math.min(delay * 2, 30000);
+    refresh().catch(() => {});
+  }
+}
+
+function handleEvent(block) {
+  let type = "", data = "";
+  for (const line of block.split("\n")) {
+    if (line.startsWith("event: ")) type = line.slice(7);
+    else if (line.startsWith("data: ")) data += line.slice(6);
+  }
+  if (!type || !data) return;
+  const v = JSON.parse(data);
+  switch (type) {
+    case "snapshot": renderSnapshot(v); appendSnapshot(v); break;
+    case "policy": renderPolicy(v); break;
+    case "audit": prependAudit(v); break;
+  }
+}
+
+// --- admin controls ----------------------------------------------------------
+
+async function admin(path, body) {
+  const out = $("admin-result");
+  body.reason = $("reason").value;
+  if (path.startsWith("/api/v1/actions/")) body.dry_run = $("dry-run").checked;
+  try {
+    const res = await api(path, { method: "POST", body: JSON.stringify(body) });
+    out.textContent = JSON.stringify(res, null, 2);
+    refresh().catch(() => {});
+  } catch (err) {
+    out.textContent = "Error: " + err.message;
+  }
+}
+
+function setupAdmin() {
+  document.querySelectorAll("[data-policy]").forEach((b) =>
+    b.addEventListener("click", () => admin("/api/v1/policies/fps_guard/" + b.dataset.policy, {})));
+  $("restore").addEventListener("click", () => {
+    if ($("dry-run").checked || confirm("Restore all baseline prefs now?")) admin("/api/v1/actions/restore-baseline", {});
+  });
+  $("set-pref").addEventListener("submit", (e) => {
+    e.preventDefault();
+    const f = e.target.elements;
+    admin("/api/v1/actions/set-game-pref", { pref: f.pref.value, value: f.pref_value.value });
+  });
+  $("say").addEventListener("submit", (e) => {
+    e.preventDefault();
+    admin("/api/v1/actions/say", { message: e.target.elements.message.value });
+  });
+}
+
+// --- startup -----------------------------------------------------------------
+
+async function connect() {
+  if (state.abort) state.abort.abort();
+  try {
+    const who = await api("/api/v1/whoami");
+    showError(null);
+    $("scope").textContent = who.auth_enabled ? who.scope : "no auth";
+    $("logout").hidden = !state.token;
+    state.admin = who.admin;
+    $("admin").hidden = !who.admin;
+    await Promise.all([refresh(), loadHistory(), loadAudit()]);
+    follow();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+$("login").addEventListener("submit", (e) => {
+  e.preventDefault();
+  state.token = $("token").value.trim();
+  $("token").value = "";
+  sessionStorage.setItem("mg7d-token", state.token);
+  connect();
+});
+$("logout").addEventListener("click", () => {
+  sessionStorage.removeItem("mg7d-token");
+  state.token = "";
+  location.reload();
+});
+$("window").addEventListener("change", () => loadHistory().catch(showError));
+window.addEventListener("resize", drawCharts);
+setInterval(() => refresh().catch(() => {}), 15000);
+setupAdmin();
+connect();
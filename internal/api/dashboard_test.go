@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	s := NewServer("127.0.0.1:0", Deps{Instance: "main", AuthToken: "admin-secret"})
+
+	// The assets are served without a token; the API still needs one.
+	for _, path := range []string{"/ui/", "/ui/app.js", "/ui/style.css"} {
+		rec := getJSON(t, s.Handler(), path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d", path, rec.Code)
+		}
+		h := rec.Header()
+		if !strings.Contains(h.Get("Content-Security-Policy"), "default-src 'none'") || h.Get("X-Frame-Options") != "DENY" || h.Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("GET %s: headers %v", path, h)
+		}
+	}
+	if rec := getJSON(t, s.Handler(), "/ui/", nil); !strings.Contains(rec.Body.String(), `<script src="app.js">`) {
+		t.Errorf("index: %s", rec.Body)
+	}
+	if rec := getJSON(t, s.Handler(), "/", nil); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/ui/" {
+		t.Errorf("GET /: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := getJSON(t, s.Handler(), "/ui/missing.js", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing asset: %d", rec.Code)
+	}
+	if rec := getJSON(t, s.Handler(), "/api/v1/status", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without token: %d", rec.Code)
+	}
+
+	off := NewServer("127.0.0.1:0", Deps{Instance: "main", DisableDashboard: true})
+	for _, path := range []string{"/", "/ui/"} {
+		if rec := getJSON(t, off.Handler(), path, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("disabled: GET %s: %d", path, rec.Code)
+		}
+	}
+}
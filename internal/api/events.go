@@ -0,0 +1,251 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// Event types streamed by GET /api/v1/events.
+const (
+	EventSnapshot = "snapshot" // every parsed Time line (Snapshot)
+	EventPlayers  = "players"  // the player count changed (PlayersEvent)
+	EventPolicy   = "policy"   // a policy transition (PolicyStatus)
+	EventAudit    = "audit"    // every audit record (AuditEvent)
+)
+
+var eventTypes = []string{EventSnapshot, EventPlayers, EventPolicy, EventAudit}
+
+// eventWriteTimeout bounds a single write to a client, so a stalled connection
+// cannot keep its handler alive.
+const eventWriteTimeout = 10 * time.Second
+
+// EventOptions bound the event stream. Zero values use the defaults.
+type EventOptions struct {
+	BufferSize int           // events queued per client before it is disconnected; default 64
+	MaxClients int           // concurrent streams; default 16
+	Heartbeat  time.Duration // keep-alive comment interval; default 15s
+}
+
+// event is one published event, encoded once and shared by all subscribers.
+type event struct {
+	id   uint64
+	typ  string
+	data []byte
+}
+
+// subscriber is one client's stream. The hub closes ch when the client falls
+// BufferSize events behind (slow is set first) or when the hub closes.
+type subscriber struct {
+	ch    chan event
+	types map[string]bool // nil means all types
+	slow  bool
+}
+
+// hub fans published events out to subscribers without blocking the publisher.
+type hub struct {
+	opts EventOptions
+
+	mu      sync.Mutex
+	clients map[*subscriber]struct{}
+	seq     uint64
+	closed  bool
+	slow    uint64 // clients disconnected for falling behind
+
+	players     int // last published player count
+	havePlayers bool
+}
+
+func newHub(opts EventOptions) *hub {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 64
+	}
+	if opts.MaxClients <= 0 {
+		opts.MaxClients = 16
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	return &hub{opts: opts, clients: make(map[*subscriber]struct{})}
+}
+
+func (h *hub) subscribe(types map[string]bool) (*subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, fmt.Errorf("server shutting down")
+	}
+	if len(h.clients) >= h.opts.MaxClients {
+		return nil, fmt.Errorf("too many event streams (max %d)", h.opts.MaxClients)
+	}
+	sub := &subscriber{ch: make(chan event, h.opts.BufferSize), types: types}
+	h.clients[sub] = struct{}{}
+	return sub, nil
+}
+
+func (h *hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[sub]; ok {
+		delete(h.clients, sub)
+		close(sub.ch)
+	}
+}
+
+// publish encodes v and queues it for every subscriber of typ. Subscribers whose
+// buffer is full are disconnected instead of blocking the caller.
+func (h *hub) publish(typ string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	ev := event{id: h.seq, typ: typ, data: data}
+	for sub := range h.clients {
+		if sub.types != nil && !sub.types[typ] {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.slow = true
+			delete(h.clients, sub)
+			close(sub.ch)
+			h.slow++
+		}
+	}
+}
+
+// stats returns the number of connected clients and slow-consumer disconnects.
+func (h *hub) stats() (clients int, slow uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients), h.slow
+}
+
+// close disconnects every subscriber and refuses new ones.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.clients {
+		delete(h.clients, sub)
+		close(sub.ch)
+	}
+}
+
+// PublishSnapshot streams a parsed snapshot, and a players event when the player
+// count changed since the previous snapshot.
+func (s *Server) PublishSnapshot(snap state.Snapshot) {
+	s.hub.publish(EventSnapshot, SnapshotOf(snap, s.now()))
+	h := s.hub
+	h.mu.Lock()
+	prev, had := h.players, h.havePlayers
+	h.players, h.havePlayers = snap.Players, true
+	h.mu.Unlock()
+	if had && prev != snap.Players {
+		s.hub.publish(EventPlayers, PlayersEvent{
+			Timestamp: snap.Timestamp,
+			Players:   snap.Players,
+			Previous:  prev,
+			Delta:     snap.Players - prev,
+		})
+	}
+}
+
+// PublishPolicy streams the policy status; pass it to policy.Engine.OnChange.
+func (s *Server) PublishPolicy(*policy.State) {
+	s.hub.publish(EventPolicy, s.policyStatus(s.now()))
+}
+
+// AuditSink returns a sink that streams audit records; add it to the audit ring.
+func (s *Server) AuditSink() state.AuditSink {
+	return auditEventSink{s.hub}
+}
+
+type auditEventSink struct{ h *hub }
+
+func (a auditEventSink) Append(ev state.AuditEvent) error {
+	a.h.publish(EventAudit, AuditEventOf(ev))
+	return nil
+}
+
+func (a auditEventSink) Close() error { return nil }
+
+// handleEvents serves GET /api/v1/events as a server-sent events stream.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	types, err := parseEventTypes(r.URL.Query().Get("types"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sub, err := s.hub.subscribe(types)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer s.hub.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	write := func(msg string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if _, err := w.Write([]byte(msg)); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write("retry: 5000\n\n") {
+		return
+	}
+	heartbeat := time.NewTicker(s.hub.opts.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case ev, ok := <-sub.ch:
+			if !ok {
+				if sub.slow {
+					write("event: error\ndata: {\"error\":\"slow consumer, disconnected\"}\n\n")
+				}
+				return
+			}
+			if !write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", ev.id, ev.typ, ev.data)) {
+				return
+			}
+		}
+	}
+}
+
+// parseEventTypes parses the comma-separated types parameter; empty means all.
+func parseEventTypes(s string) (map[string]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	types := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if !slices.Contains(eventTypes, t) {
+			return nil, fmt.Errorf("unknown event type %q (%s)", t, strings.Join(eventTypes, ", "))
+		}
+		types[t] = true
+	}
+	return types, nil
+}
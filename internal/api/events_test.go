@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// readEvent returns the type and data of the next event on the stream, skipping
+// comments and retry lines.
+func readEvent(t *testing.T, sc *bufio.Scanner) (typ, data string) {
+	t.Helper()
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && typ != "":
+			return typ, data
+		}
+	}
+	t.Fatalf("stream ended: %v", sc.Err())
+	return "", ""
+}
+
+func TestEventsStream(t *testing.T) {
+	ring := state.NewAuditRing(10)
+	s := NewServer("127.0.0.1:0", Deps{Instance: "main", Audit: ring})
+	ring.AddSink("events", s.AuditSink(), state.AuditFilter{})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/events?types=snapshot,players,audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		if n, _ := s.hub.stats(); n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client never subscribed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	now := time.Now()
+	s.PublishSnapshot(state.Snapshot{ParsedAt: now, Players: 3, EntitiesActive: -1})
+	s.PublishSnapshot(state.Snapshot{ParsedAt: now, Players: 5, EntitiesActive: -1})
+	s.PublishPolicy(nil) // filtered out
+	ring.Append(state.AuditEvent{ActionID: "act-1", Status: "queued"})
+
+	sc := bufio.NewScanner(resp.Body)
+	var got []string
+	for i := 0; i < 4; i++ {
+		typ, _ := readEvent(t, sc)
+		got = append(got, typ)
+	}
+	if strings.Join(got, ",") != "snapshot,snapshot,players,audit" {
+		t.Errorf("events: %v", got)
+	}
+
+	if rec := getJSON(t, s.Handler(), "/api/v1/events?types=bogus", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown type: %d", rec.Code)
+	}
+}
+
+func TestHubSlowConsumer(t *testing.T) {
+	h := newHub(EventOptions{BufferSize: 2, MaxClients: 1})
+	slow, err := h.subscribe(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.subscribe(nil); err == nil {
+		t.Error("subscribed past max_clients")
+	}
+	for i := 0; i < 3; i++ {
+		h.publish(EventAudit, i)
+	}
+	n := 0
+	for range slow.ch {
+		n++
+	}
+	if n != 2 || !slow.slow {
+		t.Errorf("received %d events, slow=%v", n, slow.slow)
+	}
+	if clients, disconnects := h.stats(); clients != 0 || disconnects != 1 {
+		t.Errorf("stats: %d clients, %d slow", clients, disconnects)
+	}
+	h.unsubscribe(slow) // already removed; must not close twice
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Health levels, worst last.
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// authenticateGrace is how long a new telnet connection may wait for the answer to
+// its password before it counts as unauthenticated.
+const authenticateGrace = 10 * time.Second
+
+// HealthOptions are the thresholds of /readyz and /api/v1/health. Zero values use
+// the defaults.
+type HealthOptions struct {
+	MaxLineAge       time.Duration // default 5m
+	MaxSnapshotAge   time.Duration // default 5m
+	StartupGrace     time.Duration // default 2m
+	ApplierQueueWarn float64       // fraction of capacity; default 0.5
+	ApplierQueueFail float64       // default 0.9
+	TelnetOptional   bool          // telnet problems warn instead of failing
+}
+
+func (o *HealthOptions) defaults() {
+	if o.MaxLineAge <= 0 {
+		o.MaxLineAge = 5 * time.Minute
+	}
+	if o.MaxSnapshotAge <= 0 {
+		o.MaxSnapshotAge = 5 * time.Minute
+	}
+	if o.StartupGrace <= 0 {
+		o.StartupGrace = 2 * time.Minute
+	}
+	if o.ApplierQueueWarn <= 0 {
+		o.ApplierQueueWarn = 0.5
+	}
+	if o.ApplierQueueFail <= 0 {
+		o.ApplierQueueFail = 0.9
+	}
+}
+
+// handleReadyz serves GET /readyz: 200 "ready" unless a component fails, else 503
+// with the failing components.
+func (s *Server) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	h := s.health()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if h.Status == HealthFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		var failed []string
+		for _, name := range []string{"source", "parser", "telnet", "applier"} {
+			if c, ok := h.Components[name]; ok && c.Status == HealthFail {
+				failed = append(failed, name+": "+c.Message)
+			}
+		}
+		_, _ = fmt.Fprintf(w, "not ready\n%s\n", strings.Join(failed, "\n"))
+		return
+	}
+	_, _ = w.Write([]byte("ready\n"))
+}
+
+// handleHealth serves GET /api/v1/health: the per-component report, with 503 when a
+// component fails.
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	h := s.health()
+	code := http.StatusOK
+	if h.Status == HealthFail {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, h)
+}
+
+func (s *Server) health() HealthResponse {
+	now := s.now()
+	o := s.deps.Health
+	starting := now.Sub(s.deps.StartedAt) < o.StartupGrace
+	h := HealthResponse{Status: HealthOK, Now: now, Components: make(map[string]ComponentHealth)}
+	add := func(name string, c ComponentHealth) {
+		h.Components[name] = c
+		if rank(c.Status) > rank(h.Status) {
+			h.Status = c.Status
+		}
+	}
+
+	if s.deps.Source != nil {
+		st := s.deps.Source.Stats()
+		add("source", ageHealth(st.Open, st.LastLineAt, now, o.MaxLineAge, starting, "log line"))
+	}
+	if s.deps.Snapshots != nil {
+		add("parser", ageHealth(true, s.deps.Snapshots.Current().ParsedAt, now, o.MaxSnapshotAge, starting, "Time line"))
+	}
+	if s.deps.Telnet != nil {
+		st := s.deps.Telnet.Status()
+		bad := HealthFail
+		if o.TelnetOptional {
+			bad = HealthWarn
+		}
+		c := ComponentHealth{Status: HealthOK, Message: "connected and authenticated"}
+		switch {
+		case !st.Connected:
+			c = ComponentHealth{Status: bad, Message: "not connected"}
+			if st.LastError != "" {
+				c.Message += ": " + st.LastError
+			}
+		case !st.Authenticated && now.Sub(st.ConnectedAt) < authenticateGrace:
+			c.Message = "connected, waiting for logon"
+		case !st.Authenticated:
+			c = ComponentHealth{Status: bad, Message: "connected but not authenticated"}
+			if st.LastError != "" {
+				c.Message += ": " + st.LastError
+			}
+		case st.BreakerOpen:
+			c = ComponentHealth{Status: bad, Message: fmt.Sprintf("circuit breaker open for %.0fs", st.BreakerUntil.Sub(now).Seconds())}
+		}
+		add("telnet", c)
+	}
+	if s.deps.Applier != nil {
+		queued, capacity := s.deps.Applier.QueueLen(), s.deps.Applier.QueueCap()
+		fill := float64(queued) / float64(capacity)
+		c := ComponentHealth{Status: HealthOK, Message: fmt.Sprintf("%d of %d queued", queued, capacity)}
+		switch {
+		case fill >= o.ApplierQueueFail:
+			c.Status = HealthFail
+		case fill >= o.ApplierQueueWarn:
+			c.Status = HealthWarn
+		}
+		add("applier", c)
+	}
+	return h
+}
+
+// ageHealth checks that something last happened at last, no more than maxAge ago.
+// Nothing yet is ok while the agent is starting.
+func ageHealth(open bool, last, now time.Time, maxAge time.Duration, starting bool, what string) ComponentHealth {
+	c := ComponentHealth{Status: HealthOK}
+	switch {
+	case !open:
+		c.Status, c.Message = HealthFail, "input not open"
+	case last.IsZero() && starting:
+		c.Message = "starting, no " + what + " yet"
+	case last.IsZero():
+		c.Status, c.Message = HealthFail, "no "+what+" since start"
+	default:
+		age := now.Sub(last)
+		c.AgeSeconds = age.Seconds()
+		c.Message = fmt.Sprintf("last %s %.0fs ago", what, age.Seconds())
+		if age > maxAge {
+			c.Status = HealthFail
+			c.Message += fmt.Sprintf(" (max %.0fs)", maxAge.Seconds())
+		}
+	}
+	return c
+}
+
+func rank(status string) int {
+	switch status {
+	case HealthWarn:
+		return 1
+	case HealthFail:
+		return 2
+	}
+	return 0
+}
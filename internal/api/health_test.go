@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/logtail"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// fakeSource is a logtail.Source reporting fixed stats.
+type fakeSource struct{ stats logtail.Stats }
+
+func (f *fakeSource) Lines() <-chan logtail.Line    { return nil }
+func (f *fakeSource) Run(ctx context.Context) error { return nil }
+func (f *fakeSource) Stats() logtail.Stats          { return f.stats }
+
+func TestHealth(t *testing.T) {
+	now := time.Now()
+	src := &fakeSource{stats: logtail.Stats{Open: true}}
+	snaps := state.NewSnapshotStore()
+	applier := actions.NewApplier(nil, state.NewAuditRing(10), 4)
+	s := NewServer("127.0.0.1:0", Deps{
+		Source:    src,
+		Snapshots: snaps,
+		Applier:   applier,
+		StartedAt: now,
+		Health:    HealthOptions{MaxLineAge: time.Minute, MaxSnapshotAge: time.Minute, StartupGrace: time.Minute},
+	})
+	health := func() (int, HealthResponse) {
+		var h HealthResponse
+		rec := getJSON(t, s.Handler(), "/api/v1/health", nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, h
+	}
+
+	// Starting: no line or snapshot yet is fine.
+	if code, h := health(); code != http.StatusOK || h.Status != HealthOK {
+		t.Fatalf("starting: %d %+v", code, h)
+	}
+
+	// Past the grace period with nothing parsed.
+	s.now = func() time.Time { return now.Add(2 * time.Minute) }
+	code, h := health()
+	if code != http.StatusServiceUnavailable || h.Components["source"].Status != HealthFail || h.Components["parser"].Status != HealthFail {
+		t.Fatalf("stalled: %d %+v", code, h)
+	}
+	rec := getJSON(t, s.Handler(), "/readyz", nil)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "parser: no Time line since start") {
+		t.Errorf("readyz: %d %q", rec.Code, rec.Body)
+	}
+
+	// Fresh data, applier half full: ready with a warning.
+	src.stats.LastLineAt = now.Add(110 * time.Second)
+	snaps.Update(state.Snapshot{ParsedAt: now.Add(110 * time.Second)})
+	for i := 0; i < 2; i++ {
+		_ = applier.Enqueue(context.Background(), actions.NewSay("a", "main", "", "hi"))
+	}
+	code, h = health()
+	if code != http.StatusOK || h.Status != HealthWarn || h.Components["applier"].Status != HealthWarn || h.Components["parser"].AgeSeconds != 10 {
+		t.Fatalf("warn: %d %+v", code, h)
+	}
+	if rec := getJSON(t, s.Handler(), "/readyz", nil); rec.Code != http.StatusOK {
+		t.Errorf("readyz with warning: %d", rec.Code)
+	}
+
+	// Input closed.
+	src.stats.Open = false
+	if _, h := health(); h.Components["source"].Message != "input not open" {
+		t.Errorf("closed source: %+v", h.Components["source"])
+	}
+}
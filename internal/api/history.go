@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+const (
+	defaultHistoryWindow = time.Hour
+	defaultHistoryPoints = 500
+	maxHistoryPoints     = 5000
+)
+
+// handleHistory serves GET /api/v1/history: snapshot fields over a window as columns,
+// thinned to at most max_points samples.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.deps.History == nil {
+		writeError(w, http.StatusNotFound, "history is not available")
+		return
+	}
+	v := r.URL.Query()
+	now := s.now()
+	window := defaultHistoryWindow
+	if p := v.Get("window"); p != "" {
+		d, err := time.ParseDuration(p)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "window must be a positive duration such as 30m or 6h")
+			return
+		}
+		window = d
+	}
+	fields := state.Fields
+	if p := v.Get("fields"); p != "" {
+		fields = nil
+		for _, name := range strings.Split(p, ",") {
+			f, err := state.ParseField(strings.TrimSpace(name))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			fields = append(fields, f)
+		}
+	}
+	points := defaultHistoryPoints
+	if p := v.Get("max_points"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 2 || n > maxHistoryPoints {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("max_points must be between 2 and %d", maxHistoryPoints))
+			return
+		}
+		points = n
+	}
+	writeJSON(w, http.StatusOK, historyOf(s.deps.History.Window(now, window), fields, now.Add(-window), now, points))
+}
+
+// historyOf builds the columnar response, keeping every n-th snapshot (and always the
+// newest) so at most maxPoints remain.
+func historyOf(snaps []state.Snapshot, fields []state.Field, from, to time.Time, maxPoints int) HistoryResponse {
+	stride := 1
+	if len(snaps) > maxPoints {
+		stride = (len(snaps) + maxPoints - 1) / maxPoints
+	}
+	resp := HistoryResponse{
+		From:       from,
+		To:         to,
+		Samples:    len(snaps),
+		Timestamps: []time.Time{},
+		Series:     make(map[string][]*float64, len(fields)),
+	}
+	for _, f := range fields {
+		resp.Series[string(f)] = []*float64{}
+	}
+	for i := (len(snaps) - 1) % stride; i < len(snaps); i += stride {
+		snap := snaps[i]
+		resp.Timestamps = append(resp.Timestamps, snap.Timestamp)
+		for _, f := range fields {
+			var p *float64
+			if val, ok := f.Value(snap); ok {
+				p = &val
+			}
+			resp.Series[string(f)] = append(resp.Series[string(f)], p)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func TestHistory(t *testing.T) {
+	hist := state.NewHistory(100)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		ts := now.Add(time.Duration(i-9) * time.Minute)
+		cgo := i
+		hist.Add(state.Snapshot{Timestamp: ts, ParsedAt: ts, FPS: float64(20 + i), Players: i, CGo: cgo, CGoMissing: i%2 == 1, EntitiesActive: -1})
+	}
+	s := NewServer("127.0.0.1:0", Deps{Instance: "main", History: hist})
+
+	var resp HistoryResponse
+	if rec := getJSON(t, s.Handler(), "/api/v1/history?window=30m&fields=fps,cgo", &resp); rec.Code != http.StatusOK {
+		t.Fatalf("history: %d %s", rec.Code, rec.Body)
+	}
+	if resp.Samples != 10 || len(resp.Timestamps) != 10 || len(resp.Series) != 2 {
+		t.Fatalf("history: %+v", resp)
+	}
+	if fps := resp.Series["fps"]; *fps[0] != 20 || *fps[9] != 29 {
+		t.Errorf("fps series: %v %v", *fps[0], *fps[9])
+	}
+	if cgo := resp.Series["cgo"]; cgo[0] == nil || cgo[1] != nil {
+		t.Errorf("cgo series should be null where missing: %v", cgo)
+	}
+
+	getJSON(t, s.Handler(), "/api/v1/history?window=4m30s&fields=players", &resp)
+	if resp.Samples != 5 || *resp.Series["players"][0] != 5 {
+		t.Errorf("window: %d samples, first %v", resp.Samples, *resp.Series["players"][0])
+	}
+
+	// Thinning keeps the newest snapshot.
+	getJSON(t, s.Handler(), "/api/v1/history?fields=players&max_points=3", &resp)
+	got := resp.Series["players"]
+	if len(got) != 3 || *got[len(got)-1] != 9 {
+		t.Errorf("thinned: %d points, last %v", len(got), *got[len(got)-1])
+	}
+
+	for _, q := range []string{"window=-1h", "window=x", "fields=nope", "max_points=1", "max_points=5001"} {
+		if rec := getJSON(t, s.Handler(), "/api/v1/history?"+q, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", q, rec.Code)
+		}
+	}
+	if rec := getJSON(t, NewServer("127.0.0.1:0", Deps{}).Handler(), "/api/v1/history", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("without history: %d", rec.Code)
+	}
+}
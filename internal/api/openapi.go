@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes every route registered by NewServer except /metrics and the
+// dashboard. TestOpenAPICoversRoutes keeps the two in sync.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handleOpenAPI serves GET /api/v1/openapi.json.
+func handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec)
+}
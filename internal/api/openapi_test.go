@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Tags []string `json:"tags"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	var documented []string
+	tags := make(map[string]string)
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			route := strings.ToUpper(method) + " " + path
+			documented = append(documented, route)
+			tags[route] = strings.Join(op.Tags, ",")
+		}
+	}
+
+	s := NewServer("127.0.0.1:0", Deps{Metrics: http.NotFoundHandler()})
+	var registered []string
+	for p, need := range s.routes {
+		if p == s.deps.MetricsPath {
+			continue // path is configurable
+		}
+		registered = append(registered, p)
+		// The tag tells clients which token the route needs.
+		want := need.String()
+		if need == scopeNone {
+			want = "probe"
+		}
+		if tags[p] != "" && tags[p] != want {
+			t.Errorf("%s: tagged %q in openapi.json, but needs %s", p, tags[p], want)
+		}
+	}
+	sort.Strings(documented)
+	sort.Strings(registered)
+	if strings.Join(documented, "\n") != strings.Join(registered, "\n") {
+		t.Errorf("openapi.json paths:\n%s\n\nregistered routes:\n%s", strings.Join(documented, "\n"), strings.Join(registered, "\n"))
+	}
+
+	rec := getJSON(t, s.Handler(), "/api/v1/openapi.json", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" || !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("GET openapi.json: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// TestOpenAPISchemasMatchTypes checks each schema's properties against the JSON field
+// names of the wire type it documents.
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	types := map[string]any{
+		"Error":                  ErrorResponse{},
+		"StatusResponse":         StatusResponse{},
+		"Snapshot":               Snapshot{},
+		"PolicyStatus":           PolicyStatus{},
+		"FPSGuardStatus":         FPSGuardStatus{},
+		"TelnetStatus":           TelnetStatus{},
+		"ApplierStatus":          ApplierStatus{},
+		"SourceStatus":           SourceStatus{},
+		"EventsStatus":           EventsStatus{},
+		"HealthResponse":         HealthResponse{},
+		"ComponentHealth":        ComponentHealth{},
+		"HistoryResponse":        HistoryResponse{},
+		"WhoamiResponse":         WhoamiResponse{},
+		"AuditPage":              AuditPage{},
+		"AuditEvent":             AuditEvent{},
+		"PrefChange":             PrefChange{},
+		"PlayersEvent":           PlayersEvent{},
+		"PolicyRequest":          PolicyRequest{},
+		"PolicyStateResponse":    PolicyStateResponse{},
+		"RestoreBaselineRequest": RestoreBaselineRequest{},
+		"SetGamePrefRequest":     SetGamePrefRequest{},
+		"SayRequest":             SayRequest{},
+		"ActionResponse":         ActionResponse{},
+	}
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	for name, schema := range spec.Components.Schemas {
+		v, ok := types[name]
+		if !ok {
+			t.Errorf("schema %s: no wire type to check it against", name)
+			continue
+		}
+		var documented, fields []string
+		for p := range schema.Properties {
+			documented = append(documented, p)
+		}
+		rt := reflect.TypeOf(v)
+		for i := 0; i < rt.NumField(); i++ {
+			tag, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+			if tag != "" && tag != "-" {
+				fields = append(fields, tag)
+			}
+		}
+		sort.Strings(documented)
+		sort.Strings(fields)
+		if strings.Join(documented, " ") != strings.Join(fields, " ") {
+			t.Errorf("schema %s properties:\n  %s\n%s fields:\n  %s", name, strings.Join(documented, " "), rt.Name(), strings.Join(fields, " "))
+		}
+	}
+	for name := range types {
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("openapi.json has no %s schema", name)
+		}
+	}
+}
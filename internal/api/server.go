@@ -2,45 +2,182 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/logtail"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+	"github.com/mg7d/mg7d/internal/telnet"
 )
 
-// MetricsServer is an HTTP server that only serves /metrics.
-type MetricsServer struct {
-	srv *http.Server
+// Deps are the agent components the API reads from. Nil components are reported as
+// absent rather than failing requests.
+type Deps struct {
+	Instance    string
+	SourceType  string
+	SourcePath  string
+	Snapshots   *state.SnapshotStore
+	History     *state.History
+	Source      logtail.Source
+	Policy      *policy.Engine
+	Telnet      *telnet.Client   // nil when telnet is not configured
+	Applier     *actions.Applier // nil when telnet is not configured
+	Audit       *state.AuditRing
+	AuditLog    *state.AuditLog // persistent audit store; nil when audit.file.path is unset
+	Metrics     http.Handler    // served at MetricsPath; nil disables /metrics
+	MetricsPath string
+	AuthToken   string   // admin-scope bearer token; with ReadToken empty, auth is disabled
+	ReadToken   string   // read-scope bearer token
+	AuthExempt  []string // paths served without a token
+	Events      EventOptions
+	Health      HealthOptions
+	StartedAt   time.Time
+
+	DisableDashboard bool // do not serve the web dashboard at /ui/
 }
 
-// NewMetricsServer creates a server that serves handler at path (e.g. "/metrics") and GET /healthz.
-func NewMetricsServer(listen, path string, handler http.Handler) *MetricsServer {
-	if path == "" {
-		path = "/metrics"
+// Server is the agent's HTTP server: /metrics, /healthz and the versioned JSON API
+// under /api/v1. When a token is configured every path not in AuthExempt requires
+// "Authorization: Bearer <token>".
+type Server struct {
+	deps Deps
+	auth *authenticator
+	hub  *hub
+	mux  *http.ServeMux
+	srv  *http.Server
+	now  func() time.Time
+
+	certs      *certReloader      // nil without TLS
+	reloadCtx  context.Context    // lifetime of the certificate watcher
+	stopReload context.CancelFunc // cancels reloadCtx on Shutdown
+
+	ids    *actions.IDSource // IDs of actions created through the API
+	routes map[string]scope  // patterns registered with handle and the scope they need
+}
+
+// NewServer creates a server listening on listen.
+func NewServer(listen string, deps Deps) *Server {
+	if deps.MetricsPath == "" {
+		deps.MetricsPath = "/metrics"
+	}
+	if deps.StartedAt.IsZero() {
+		deps.StartedAt = time.Now()
 	}
-	mux := http.NewServeMux()
-	mux.Handle(path, handler)
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+	deps.Health.defaults()
+	s := &Server{
+		deps:   deps,
+		auth:   newAuthenticator(deps.AuthToken, deps.ReadToken, deps.AuthExempt),
+		hub:    newHub(deps.Events),
+		mux:    http.NewServeMux(),
+		now:    time.Now,
+		ids:    actions.NewIDSource("api"),
+		routes: make(map[string]scope),
+	}
+	if deps.Metrics != nil {
+		s.handle(deps.MetricsPath, scopeRead, deps.Metrics)
+	}
+	s.handle("GET /healthz", scopeNone, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
-	})
-	return &MetricsServer{
-		srv: &http.Server{
-			Addr:    listen,
-			Handler: mux,
-		},
+	}))
+	s.handle("GET /readyz", scopeNone, http.HandlerFunc(s.handleReadyz))
+	s.handle("GET /api/v1/openapi.json", scopeRead, http.HandlerFunc(handleOpenAPI))
+	s.handle("GET /api/v1/status", scopeRead, http.HandlerFunc(s.handleStatus))
+	s.handle("GET /api/v1/health", scopeRead, http.HandlerFunc(s.handleHealth))
+	s.handle("GET /api/v1/history", scopeRead, http.HandlerFunc(s.handleHistory))
+	s.handle("GET /api/v1/whoami", scopeRead, http.HandlerFunc(s.handleWhoami))
+	s.handle("GET /api/v1/audit", scopeRead, http.HandlerFunc(s.handleAudit))
+	s.handle("GET /api/v1/events", scopeRead, http.HandlerFunc(s.handleEvents))
+	s.handle("POST /api/v1/policies/{name}/pause", scopeAdmin, s.handlePolicyPause(true))
+	s.handle("POST /api/v1/policies/{name}/resume", scopeAdmin, s.handlePolicyPause(false))
+	s.handle("POST /api/v1/actions/restore-baseline", scopeAdmin, http.HandlerFunc(s.handleRestoreBaseline))
+	s.handle("POST /api/v1/actions/set-game-pref", scopeAdmin, http.HandlerFunc(s.handleSetGamePref))
+	s.handle("POST /api/v1/actions/say", scopeAdmin, http.HandlerFunc(s.handleSay))
+	if !deps.DisableDashboard {
+		s.mux.Handle("GET /ui/", handleDashboard())
+		s.mux.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+	}
+	s.srv = &http.Server{
+		Addr:              listen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	return s
+}
+
+// Handler returns the server's HTTP handler (for tests and embedding).
+func (s *Server) Handler() http.Handler {
+	return s.srv.Handler
+}
+
+// EnableTLS makes the server serve HTTPS with the given certificate files. It must be
+// called before ListenAndServe or Serve.
+func (s *Server) EnableTLS(opts TLSOptions) error {
+	r := &certReloader{opts: opts}
+	if err := r.load(); err != nil {
+		return err
+	}
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	s.certs = r
+	s.srv.TLSConfig = cfg
+	s.reloadCtx, s.stopReload = context.WithCancel(context.Background())
+	return nil
 }
 
 // ListenAndServe starts the server (blocks).
-func (s *MetricsServer) ListenAndServe() error {
-	return s.srv.ListenAndServe()
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln (blocks). With TLS enabled, certificate files are
+// watched and reloaded while serving.
+func (s *Server) Serve(ln net.Listener) error {
+	if s.certs == nil {
+		return s.srv.Serve(ln)
+	}
+	go func() {
+		if err := s.certs.watch(s.reloadCtx); err != nil && s.certs.opts.OnReload != nil {
+			s.certs.opts.OnReload(err)
+		}
+	}()
+	return s.srv.ServeTLS(ln, "", "")
 }
 
 // Shutdown gracefully shuts down the server.
-func (s *MetricsServer) Shutdown(ctx context.Context) error {
+func (s *Server) Shutdown(ctx context.Context) error {
 	if s.srv == nil {
 		return nil
 	}
+	s.hub.close() // end event streams so Shutdown does not wait for them
+	if s.stopReload != nil {
+		s.stopReload()
+	}
 	ctx2, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	return s.srv.Shutdown(ctx2)
 }
+
+// writeJSON writes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError writes {"error": msg} with the given status code.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, ErrorResponse{Error: msg})
+}
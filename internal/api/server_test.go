@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func testInstance() config.Instance {
+	return config.Instance{
+		Name: "main",
+		Policy: config.Policy{FPSGuard: &config.FPSGuardPolicy{
+			Enabled:              true,
+			ThresholdLow:         25,
+			ThresholdRestore:     40,
+			RequireLowSamples:    3,
+			SampleWindowSamples:  10,
+			RestoreStableSeconds: 120,
+			CooldownSeconds:      60,
+			ThrottleProfile:      "default",
+		}},
+		Actions: config.ActionsCfg{ThrottleProfiles: map[string]config.ThrottleProfile{
+			"default": {Steps: []config.ThrottleStep{{Pref: "A", Value: "1"}, {Pref: "A", Value: "2"}}},
+		}},
+	}
+}
+
+func getJSON(t *testing.T, h http.Handler, path string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v: %s", path, err, rec.Body)
+		}
+	}
+	return rec
+}
+
+func TestStatus(t *testing.T) {
+	snaps := state.NewSnapshotStore()
+	engine := policy.NewEngine("main", testInstance())
+	s := NewServer("127.0.0.1:0", Deps{Instance: "main", Snapshots: snaps, Policy: engine})
+
+	var st StatusResponse
+	getJSON(t, s.Handler(), "/api/v1/status", &st)
+	if st.Instance != "main" || st.Snapshot != nil || st.Telnet != nil || st.Policy.FPSGuard == nil {
+		t.Fatalf("empty status: %+v", st)
+	}
+
+	now := time.Now()
+	snap := state.Snapshot{ParsedAt: now, Timestamp: now, FPS: 12, EntitiesActive: -1, CGoMissing: true}
+	snaps.Update(snap)
+	for i := 0; i < 3; i++ {
+		engine.Evaluate(policy.Input{Snapshot: snap})
+	}
+	getJSON(t, s.Handler(), "/api/v1/status", &st)
+	if st.Snapshot == nil || st.Snapshot.FPS != 12 || st.Snapshot.EntitiesActive != nil || st.Snapshot.CGo != nil {
+		t.Errorf("snapshot: %+v", st.Snapshot)
+	}
+	g := st.Policy.FPSGuard
+	if !g.Throttled || g.Steps != 2 || g.CooldownRemainingSeconds <= 50 {
+		t.Errorf("fps guard: %+v", g)
+	}
+
+	if rec := getJSON(t, s.Handler(), "/healthz", nil); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("healthz: %d %q", rec.Code, rec.Body)
+	}
+	if rec := getJSON(t, s.Handler(), "/metrics", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("metrics without handler: %d", rec.Code)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// handleStatus serves GET /api/v1/status.
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) status() StatusResponse {
+	now := s.now()
+	resp := StatusResponse{
+		Instance:      s.deps.Instance,
+		Now:           now,
+		StartedAt:     s.deps.StartedAt,
+		UptimeSeconds: now.Sub(s.deps.StartedAt).Seconds(),
+		Policy:        s.policyStatus(now),
+	}
+	if s.deps.Snapshots != nil {
+		resp.Snapshot = SnapshotOf(s.deps.Snapshots.Current(), now)
+	}
+	if s.deps.Telnet != nil {
+		resp.Telnet = telnetStatusOf(s.deps.Telnet.Status())
+	}
+	if s.deps.Applier != nil {
+		resp.Applier = &ApplierStatus{Queued: s.deps.Applier.QueueLen(), Capacity: s.deps.Applier.QueueCap()}
+	}
+	if s.deps.Source != nil {
+		resp.Source = sourceStatusOf(s.deps.SourceType, s.deps.SourcePath, s.deps.Source.Stats())
+	}
+	clients, slow := s.hub.stats()
+	resp.Events = EventsStatus{Clients: clients, SlowDisconnects: slow}
+	return resp
+}
+
+func (s *Server) policyStatus(now time.Time) PolicyStatus {
+	ps := PolicyStatus{Paused: []string{}}
+	if s.deps.Policy == nil {
+		return ps
+	}
+	if paused := s.deps.Policy.Paused(); paused != nil {
+		ps.Paused = paused
+	}
+	if st, ok := s.deps.Policy.FPSGuardStatus(now); ok {
+		ps.FPSGuard = fpsGuardStatusOf(st)
+	}
+	return ps
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay debounces reloads: certificate tools often write the cert and key in
+// separate steps.
+const reloadDelay = 250 * time.Millisecond
+
+// TLSOptions configure HTTPS. CertFile, KeyFile and ClientCAFile are reloaded when
+// they change on disk; a failed reload keeps the previous certificates.
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // enables client certificate verification (mTLS)
+	ClientAuth   string // require (default) or optional
+	MinVersion   string // 1.2 (default) or 1.3
+	OnReload     func(error)
+}
+
+// certReloader serves the current certificate and client CA pool.
+type certReloader struct {
+	opts TLSOptions
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	cas  *x509.CertPool
+}
+
+// load reads the certificate, key and client CAs. On error the previous ones stay.
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("api tls: %w", err)
+	}
+	var cas *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("api tls: %w", err)
+		}
+		cas = x509.NewCertPool()
+		if !cas.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("api tls: %s: no PEM certificates", r.opts.ClientCAFile)
+		}
+	}
+	r.mu.Lock()
+	r.cert, r.cas = &cert, cas
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// config returns the server TLS config. The client CA pool is looked up per
+// handshake so a reloaded CA file takes effect for new connections.
+func (r *certReloader) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	switch r.opts.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("api tls: min version %q invalid (1.2, 1.3)", r.opts.MinVersion)
+	}
+	if r.opts.ClientCAFile == "" {
+		return cfg, nil
+	}
+	switch r.opts.ClientAuth {
+	case "", "require":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("api tls: client auth %q invalid (require, optional)", r.opts.ClientAuth)
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := cfg.Clone()
+		c.GetConfigForClient = nil
+		r.mu.RLock()
+		c.ClientCAs = r.cas
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return cfg, nil
+}
+
+// watch reloads the files when their directories change until ctx is cancelled.
+// Directories are watched rather than files so that replacing a file by rename or a
+// symlink swap (as Kubernetes secret volumes do) is noticed.
+func (r *certReloader) watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("api tls: %w", err)
+	}
+	defer w.Close()
+	dirs := make(map[string]bool)
+	for _, f := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if f == "" || dirs[filepath.Dir(f)] {
+			continue
+		}
+		dirs[filepath.Dir(f)] = true
+		if err := w.Add(filepath.Dir(f)); err != nil {
+			return fmt.Errorf("api tls: watch %s: %w", filepath.Dir(f), err)
+		}
+	}
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Chmod) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.Errors:
+			if ok && r.opts.OnReload != nil {
+				r.opts.OnReload(fmt.Errorf("api tls: watch: %w", err))
+			}
+		case <-timer.C:
+			err := r.load()
+			if r.opts.OnReload != nil {
+				r.opts.OnReload(err)
+			}
+		}
+	}
+}
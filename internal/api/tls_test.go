@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a key pair signed by parent (self-signed when parent is nil).
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, serial int64, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "mg7d-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, c.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func serveTLS(t *testing.T, opts TLSOptions) string {
+	t.Helper()
+	s := NewServer("127.0.0.1:0", Deps{})
+	if err := s.EnableTLS(opts); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(ln)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return "https://" + ln.Addr().String()
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	ca := newTestCert(t, 1, nil, true)
+	newTestCert(t, 10, ca, false).write(t, certFile, keyFile)
+	reloaded := make(chan error, 10)
+	url := serveTLS(t, TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3", OnReload: func(err error) { reloaded <- err }})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	serial := func() int64 {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}, DisableKeepAlives: true}
+		resp, err := (&http.Client{Transport: tr}).Get(url + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.TLS.Version != tls.VersionTLS13 {
+			t.Errorf("TLS version %x", resp.TLS.Version)
+		}
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+	if got := serial(); got != 10 {
+		t.Fatalf("serial %d", got)
+	}
+
+	newTestCert(t, 11, ca, false).write(t, certFile, keyFile)
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("certificate not reloaded")
+	}
+	if got := serial(); got != 11 {
+		t.Errorf("serial after reload %d", got)
+	}
+
+	tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS12}}
+	if _, err := (&http.Client{Transport: tr}).Get(url + "/healthz"); err == nil {
+		t.Error("TLS 1.2 client accepted with min_version 1.3")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, 1, nil, true)
+	newTestCert(t, 10, ca, false).write(t, certFile, keyFile)
+	if err := os.WriteFile(caFile, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	url := serveTLS(t, TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) error {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}
+		resp, err := (&http.Client{Transport: tr}).Get(url + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(); err == nil {
+		t.Error("client without certificate accepted")
+	}
+	if err := get(newTestCert(t, 20, nil, false).tlsCert()); err == nil {
+		t.Error("client with untrusted certificate accepted")
+	}
+	if err := get(newTestCert(t, 21, ca, false).tlsCert()); err != nil {
+		t.Errorf("trusted client: %v", err)
+	}
+}
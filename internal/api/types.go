@@ -0,0 +1,154 @@
+package api
+
+import (
+	"time"
+
+	"github.com/mg7d/mg7d/internal/logtail"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+	"github.com/mg7d/mg7d/internal/telnet"
+	"github.com/mg7d/mg7d/pkg/apitypes"
+)
+
+// Wire types of the JSON API, see pkg/apitypes. They are kept separate from the
+// internal types so the API stays stable when internals change.
+type (
+	ErrorResponse          = apitypes.ErrorResponse
+	StatusResponse         = apitypes.StatusResponse
+	Snapshot               = apitypes.Snapshot
+	HealthResponse         = apitypes.HealthResponse
+	ComponentHealth        = apitypes.ComponentHealth
+	HistoryResponse        = apitypes.HistoryResponse
+	WhoamiResponse         = apitypes.WhoamiResponse
+	EventsStatus           = apitypes.EventsStatus
+	PlayersEvent           = apitypes.PlayersEvent
+	PolicyStatus           = apitypes.PolicyStatus
+	FPSGuardStatus         = apitypes.FPSGuardStatus
+	TelnetStatus           = apitypes.TelnetStatus
+	ApplierStatus          = apitypes.ApplierStatus
+	SourceStatus           = apitypes.SourceStatus
+	AuditPage              = apitypes.AuditPage
+	AuditEvent             = apitypes.AuditEvent
+	PrefChange             = apitypes.PrefChange
+	PolicyRequest          = apitypes.PolicyRequest
+	PolicyStateResponse    = apitypes.PolicyStateResponse
+	RestoreBaselineRequest = apitypes.RestoreBaselineRequest
+	SetGamePrefRequest     = apitypes.SetGamePrefRequest
+	SayRequest             = apitypes.SayRequest
+	ActionResponse         = apitypes.ActionResponse
+)
+
+// SnapshotOf converts a snapshot to its wire form, or nil if s is the zero snapshot.
+func SnapshotOf(s state.Snapshot, now time.Time) *Snapshot {
+	if s.ParsedAt.IsZero() && s.Timestamp.IsZero() {
+		return nil
+	}
+	out := &Snapshot{
+		Timestamp:   s.Timestamp,
+		ParsedAt:    s.ParsedAt,
+		AgeSeconds:  now.Sub(s.ParsedAt).Seconds(),
+		FPS:         s.FPS,
+		HeapMB:      s.HeapMB,
+		RSSMB:       s.RSSMB,
+		Chunks:      s.Chunks,
+		Players:     s.Players,
+		Zombies:     s.Zombies,
+		Entities:    s.EntitiesTotal,
+		Connections: s.CO,
+	}
+	if !s.CGoMissing {
+		v := s.CGo
+		out.CGo = &v
+	}
+	if s.EntitiesActive >= 0 {
+		v := s.EntitiesActive
+		out.EntitiesActive = &v
+	}
+	return out
+}
+
+func fpsGuardStatusOf(st policy.FPSGuardStatus) *FPSGuardStatus {
+	return &FPSGuardStatus{
+		Throttled:                st.Throttled,
+		Step:                     st.Step,
+		Steps:                    st.Steps,
+		Profile:                  st.Profile,
+		LastAction:               st.LastAction,
+		ThrottledSince:           st.LowSince,
+		CooldownRemainingSeconds: st.CooldownRemaining.Seconds(),
+		RestoreTimerStarted:      st.RestoreAt,
+		RestoreRemainingSeconds:  st.RestoreRemaining.Seconds(),
+	}
+}
+
+func telnetStatusOf(st telnet.Status) *TelnetStatus {
+	return &TelnetStatus{
+		Addr:                st.Addr,
+		Connected:           st.Connected,
+		Authenticated:       st.Authenticated,
+		ConnectedAt:         st.ConnectedAt,
+		BreakerOpen:         st.BreakerOpen,
+		BreakerUntil:        st.BreakerUntil,
+		ConsecutiveFailures: st.ConsecutiveFailures,
+		Queued:              st.Queued,
+		QueueCapacity:       st.QueueCapacity,
+		LastError:           st.LastError,
+		LastErrorAt:         st.LastErrorAt,
+	}
+}
+
+func sourceStatusOf(typ, path string, st logtail.Stats) *SourceStatus {
+	return &SourceStatus{
+		Type:       typ,
+		Path:       path,
+		Open:       st.Open,
+		Offset:     st.Offset,
+		LastLineAt: st.LastLineAt,
+		Queued:     st.Queued,
+		Capacity:   st.Capacity,
+		Dropped:    st.Dropped,
+		Repaired:   st.Repaired,
+	}
+}
+
+// AuditEventOf converts an audit event to its wire form.
+func AuditEventOf(ev state.AuditEvent) AuditEvent {
+	out := AuditEvent{
+		Seq:        ev.Seq,
+		ActionID:   ev.ActionID,
+		ActionType: ev.ActionType,
+		Status:     ev.Status,
+		Error:      ev.Error,
+		Instance:   ev.Instance,
+		Policy:     ev.Policy,
+		Caller:     ev.Caller,
+		Reason:     ev.Reason,
+		Commands:   ev.Commands,
+		QueuedAt:   ev.QueuedAt,
+		SentAt:     ev.SentAt,
+		DoneAt:     ev.DoneAt,
+	}
+	for _, c := range ev.Changes {
+		out.Changes = append(out.Changes, PrefChange{Pref: c.Pref, OldValue: c.OldValue, NewValue: c.NewValue})
+	}
+	return out
+}
+
+func actionResponseOf(ev state.AuditEvent) ActionResponse {
+	out := ActionResponse{
+		ActionID:   ev.ActionID,
+		ActionType: ev.ActionType,
+		Status:     ev.Status,
+		Caller:     ev.Caller,
+		Reason:     ev.Reason,
+		Commands:   ev.Commands,
+		Changes:    AuditEventOf(ev).Changes,
+	}
+	if out.Commands == nil {
+		out.Commands = []string{}
+	}
+	if out.Changes == nil {
+		out.Changes = []PrefChange{}
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+// Package auditsink implements audit destinations beyond the in-memory ring: HTTP
+// webhooks, RFC5424 syslog and rotating JSONL files.
+package auditsink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+const (
+	defaultBufferSize = 256
+	defaultRetries    = 3
+	retryBackoff      = 500 * time.Millisecond
+	maxRetryBackoff   = 30 * time.Second
+	closeTimeout      = 5 * time.Second
+)
+
+// errBufferFull is returned by Append when a sink cannot keep up.
+var errBufferFull = errors.New("buffer full, event dropped")
+
+// New creates the sink described by c. onError (may be nil) receives delivery
+// failures that happen after Append has returned, e.g. a webhook that still fails
+// after all retries.
+func New(c config.AuditSink, onError func(error)) (state.AuditSink, error) {
+	opts := bufferOptions{size: c.BufferSize, retries: c.MaxRetries, onError: onError}
+	switch c.Type {
+	case "webhook":
+		return NewWebhook(WebhookOptions{
+			URL:     c.Webhook.URL,
+			Headers: c.Webhook.Headers,
+			Timeout: time.Duration(c.Webhook.TimeoutSeconds * float64(time.Second)),
+		}, opts)
+	case "syslog":
+		return NewSyslog(SyslogOptions{
+			Address:  c.Syslog.Address,
+			Protocol: c.Syslog.Protocol,
+			AppName:  c.Syslog.AppName,
+			Facility: c.Syslog.Facility,
+		}, opts)
+	case "file":
+		return state.OpenAuditLog(FileOptions(c.File))
+	}
+	return nil, fmt.Errorf("auditsink: unknown type %q", c.Type)
+}
+
+// Filter returns the ring filter for c.
+func Filter(c config.AuditSink) state.AuditFilter {
+	return state.AuditFilter{Statuses: c.Statuses, ActionTypes: c.ActionTypes}
+}
+
+// FileOptions converts an audit file config to state.AuditLogOptions.
+func FileOptions(f config.AuditFile) state.AuditLogOptions {
+	return state.AuditLogOptions{
+		Path:          f.Path,
+		MaxSizeBytes:  int64(f.MaxSizeMB * (1 << 20)),
+		MaxAge:        time.Duration(f.MaxAgeHours * float64(time.Hour)),
+		MaxBackups:    f.MaxBackups,
+		Fsync:         f.Fsync,
+		FsyncInterval: time.Duration(f.FsyncIntervalSeconds * float64(time.Second)),
+		HMACKey:       []byte(f.HMACKey),
+	}
+}
+
+// bufferOptions configures the delivery buffer shared by network sinks.
+type bufferOptions struct {
+	size    int // default 256
+	retries int // attempts after the first; 0 means default 3, negative disables
+	onError func(error)
+}
+
+// buffered decouples AuditRing.Append from network I/O: events go into a bounded
+// buffer and a goroutine delivers them in order, retrying with exponential backoff.
+// When the buffer is full new events are dropped and counted.
+type buffered struct {
+	ch      chan state.AuditEvent
+	deliver func(context.Context, state.AuditEvent) error
+	opts    bufferOptions
+	backoff time.Duration // first retry delay; doubled per attempt
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+}
+
+func newBuffered(opts bufferOptions, deliver func(context.Context, state.AuditEvent) error) *buffered {
+	if opts.size <= 0 {
+		opts.size = defaultBufferSize
+	}
+	if opts.retries == 0 {
+		opts.retries = defaultRetries
+	}
+	if opts.retries < 0 {
+		opts.retries = 0
+	}
+	b := &buffered{
+		ch:      make(chan state.AuditEvent, opts.size),
+		deliver: deliver,
+		opts:    opts,
+		backoff: retryBackoff,
+		done:    make(chan struct{}),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	go b.run()
+	return b
+}
+
+// Append queues ev for delivery without blocking.
+func (b *buffered) Append(ev state.AuditEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errors.New("closed")
+	}
+	select {
+	case b.ch <- ev:
+		return nil
+	default:
+		b.dropped.Add(1)
+		return errBufferFull
+	}
+}
+
+// Dropped returns events dropped because the buffer was full.
+func (b *buffered) Dropped() uint64 { return b.dropped.Load() }
+
+// Failed returns events given up on after all retries.
+func (b *buffered) Failed() uint64 { return b.failed.Load() }
+
+func (b *buffered) run() {
+	defer close(b.done)
+	for ev := range b.ch {
+		if err := b.deliverWithRetry(ev); err != nil {
+			b.failed.Add(1)
+			if b.opts.onError != nil {
+				b.opts.onError(fmt.Errorf("event %d (%s %s): %w", ev.Seq, ev.ActionID, ev.Status, err))
+			}
+		}
+	}
+}
+
+func (b *buffered) deliverWithRetry(ev state.AuditEvent) error {
+	wait := b.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = b.deliver(b.ctx, ev); err == nil {
+			return nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || attempt >= b.opts.retries {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-b.ctx.Done():
+			return err
+		}
+		wait = min(wait*2, maxRetryBackoff)
+	}
+}
+
+// shutdown stops accepting events and waits up to closeTimeout for the buffer to
+// drain, then abandons in-flight retries.
+func (b *buffered) shutdown() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.ch)
+	b.mu.Unlock()
+	select {
+	case <-b.done:
+	case <-time.After(closeTimeout):
+		b.cancel()
+		<-b.done
+	}
+	b.cancel()
+}
+
+// permanentError marks a delivery failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
@@ -0,0 +1,114 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// Syslog severities used for audit events.
+const (
+	severityWarning = 4 // failure, dropped
+	severityInfo    = 6 // everything else
+)
+
+// SyslogOptions configures a syslog sink.
+type SyslogOptions struct {
+	Address  string // host:port
+	Protocol string // udp (default) or tcp
+	AppName  string // default "mg7d"
+	Facility int    // default 16 (local0)
+}
+
+// Syslog sends each audit event as an RFC5424 message whose MSG is the event's JSON.
+// TCP uses octet-counting framing (RFC6587) and reconnects on error.
+type Syslog struct {
+	*buffered
+	opts     SyslogOptions
+	hostname string
+	conn     net.Conn // used only by the delivery goroutine
+}
+
+// NewSyslog creates a syslog sink and starts its delivery goroutine. The connection is
+// opened on the first event.
+func NewSyslog(opts SyslogOptions, bopts bufferOptions) (*Syslog, error) {
+	if opts.Address == "" {
+		return nil, errors.New("auditsink: syslog address required")
+	}
+	switch opts.Protocol {
+	case "":
+		opts.Protocol = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("auditsink: syslog protocol %q invalid (udp, tcp)", opts.Protocol)
+	}
+	if opts.AppName == "" {
+		opts.AppName = "mg7d"
+	}
+	if opts.Facility == 0 {
+		opts.Facility = 16
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "-"
+	}
+	s := &Syslog{opts: opts, hostname: host}
+	s.buffered = newBuffered(bopts, s.send)
+	return s, nil
+}
+
+func (s *Syslog) send(ctx context.Context, ev state.AuditEvent) error {
+	msg, err := formatRFC5424(ev, s.opts.Facility, s.hostname, s.opts.AppName)
+	if err != nil {
+		return permanentError{err}
+	}
+	if s.conn == nil {
+		d := net.Dialer{Timeout: 5 * time.Second}
+		if s.conn, err = d.DialContext(ctx, s.opts.Protocol, s.opts.Address); err != nil {
+			return err
+		}
+	}
+	if s.opts.Protocol == "tcp" {
+		msg = fmt.Appendf(nil, "%d %s", len(msg), msg)
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := s.conn.Write(msg); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// formatRFC5424 renders "<PRI>1 TIMESTAMP HOSTNAME APP-NAME - audit - MSG".
+func formatRFC5424(ev state.AuditEvent, facility int, hostname, appName string) ([]byte, error) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	sev := severityInfo
+	if ev.Status == "failure" || ev.Status == "dropped" || ev.Status == "denied" {
+		sev = severityWarning
+	}
+	ts := "-"
+	if t := ev.Time(); !t.IsZero() {
+		ts = t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Appendf(nil, "<%d>1 %s %s %s - audit - %s", facility*8+sev, ts, hostname, appName, body), nil
+}
+
+// Close delivers buffered events (waiting up to 5s), stops the sink and closes the
+// connection.
+func (s *Syslog) Close() error {
+	s.shutdown()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
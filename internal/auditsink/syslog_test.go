@@ -0,0 +1,47 @@
+package auditsink
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatRFC5424(t *testing.T) {
+	ev := auditEvent("act-1", "failure")
+	msg, err := formatRFC5424(ev, 16, "host1", "mg7d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<132>1 2024-05-01T21:03:00Z host1 mg7d - audit - {"seq":0,"action_id":"act-1"`
+	if !strings.HasPrefix(string(msg), want) {
+		t.Errorf("got %s", msg)
+	}
+}
+
+func TestSyslog_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no listener:", err)
+	}
+	defer pc.Close()
+
+	s, err := NewSyslog(SyslogOptions{Address: pc.LocalAddr().String()}, bufferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Append(auditEvent("act-9", "success")); err != nil {
+		t.Fatal(err)
+	}
+	_ = pc.SetReadDeadline(time.Now().Add(3 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<134>1 ") || !strings.Contains(msg, `"action_id":"act-9"`) {
+		t.Errorf("got %q", msg)
+	}
+}
@@ -0,0 +1,78 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// WebhookOptions configures a webhook sink.
+type WebhookOptions struct {
+	URL     string
+	Headers map[string]string // added to every request, e.g. Authorization
+	Timeout time.Duration     // per request; default 5s
+}
+
+// Webhook POSTs each audit event as a JSON object to a URL. Network errors, 429 and
+// 5xx responses are retried; other non-2xx responses are not.
+type Webhook struct {
+	*buffered
+	opts   WebhookOptions
+	client *http.Client
+}
+
+// NewWebhook creates a webhook sink and starts its delivery goroutine.
+func NewWebhook(opts WebhookOptions, bopts bufferOptions) (*Webhook, error) {
+	if opts.URL == "" {
+		return nil, errors.New("auditsink: webhook url required")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	w := &Webhook{opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+	w.buffered = newBuffered(bopts, w.post)
+	return w, nil
+}
+
+func (w *Webhook) post(ctx context.Context, ev state.AuditEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return permanentError{err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mg7d-agent")
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook: %s", resp.Status)
+	default:
+		return permanentError{fmt.Errorf("webhook: %s", resp.Status)}
+	}
+}
+
+// Close delivers buffered events (waiting up to 5s) and stops the sink.
+func (w *Webhook) Close() error {
+	w.shutdown()
+	return nil
+}
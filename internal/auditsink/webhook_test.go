@@ -0,0 +1,102 @@
+package auditsink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func TestWebhook_RetryAndFilter(t *testing.T) {
+	var mu sync.Mutex
+	var got []state.AuditEvent
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("missing header")
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev state.AuditEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		got = append(got, ev)
+	}))
+	defer srv.Close()
+
+	wh, err := NewWebhook(WebhookOptions{URL: srv.URL, Headers: map[string]string{"X-Token": "secret"}}, bufferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh.backoff = time.Millisecond
+	ring := state.NewAuditRing(8)
+	ring.AddSink("ops", wh, state.AuditFilter{Statuses: []string{"failure"}})
+	ring.Append(auditEvent("act-1", "queued"))
+	ring.Append(auditEvent("act-1", "failure"))
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(got) != 1 || got[0].Status != "failure" || got[0].Seq != 2 {
+		t.Errorf("calls=%d got=%+v", calls, got)
+	}
+}
+
+func TestWebhook_PermanentErrorAndBufferFull(t *testing.T) {
+	block := make(chan struct{})
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var errs []error
+	var mu sync.Mutex
+	wh, err := NewWebhook(WebhookOptions{URL: srv.URL}, bufferOptions{size: 1, onError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first event is in flight, the second fills the buffer, the third is dropped.
+	_ = wh.Append(auditEvent("act-1", "queued"))
+	deadline := time.Now().Add(2 * time.Second)
+	for len(wh.ch) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	_ = wh.Append(auditEvent("act-2", "queued"))
+	if err := wh.Append(auditEvent("act-3", "queued")); err == nil {
+		t.Error("expected buffer full error")
+	}
+	close(block)
+	_ = wh.Close()
+
+	if wh.Dropped() != 1 || wh.Failed() != 2 || calls != 2 {
+		t.Errorf("dropped=%d failed=%d calls=%d (400 must not be retried)", wh.Dropped(), wh.Failed(), calls)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 2 {
+		t.Errorf("onError called %d times, want 2", len(errs))
+	}
+}
+
+func auditEvent(id, status string) state.AuditEvent {
+	return state.AuditEvent{ActionID: id, ActionType: "SetGamePref", Status: status, QueuedAt: time.Date(2024, 5, 1, 21, 3, 0, 0, time.UTC)}
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,15 +17,42 @@ type Config struct {
 	Instances []Instance `yaml:"instances"`
 	API       API        `yaml:"api"`
 	Metrics   Metrics    `yaml:"metrics"`
+	History   History    `yaml:"history"`
+	Audit     Audit      `yaml:"audit"`
+	Analysis  Analysis   `yaml:"analysis"`
+	Health    Health     `yaml:"health"`
 }
 
 // Instance is a single 7DTD server instance.
 type Instance struct {
-	Name    string       `yaml:"name"`
-	LogPath string       `yaml:"log_path"`
-	Telnet  Telnet       `yaml:"telnet"`
-	Policy  Policy       `yaml:"policy"`
-	Actions ActionsCfg   `yaml:"actions"`
+	Name    string     `yaml:"name"`
+	LogPath string     `yaml:"log_path"`
+	Source  LogSource  `yaml:"source"`
+	Telnet  Telnet     `yaml:"telnet"`
+	Policy  Policy     `yaml:"policy"`
+	Actions ActionsCfg `yaml:"actions"`
+}
+
+// LogSource selects where an instance's log lines come from.
+type LogSource struct {
+	Type      string `yaml:"type"`       // file (default), stdin, fifo, journald, syslog
+	Path      string `yaml:"path"`       // fifo or journald pipe; fifo defaults to log_path
+	Format    string `yaml:"format"`     // raw (default) or docker (json-file driver records)
+	QueueSize int    `yaml:"queue_size"` // line channel capacity; default 256
+	Overflow  string `yaml:"overflow"`   // block (default), drop_oldest, latest_time
+	Syslog    Syslog `yaml:"syslog"`     // receiver settings when type is syslog
+}
+
+// Syslog configures the built-in RFC5424/RFC3164 receiver for one instance.
+type Syslog struct {
+	Listen    string   `yaml:"listen"`    // e.g. ":5514"
+	Protocol  string   `yaml:"protocol"`  // udp (default), tcp, both
+	AppNames  []string `yaml:"app_names"` // accept only these APP-NAME/TAG values
+	Hostnames []string `yaml:"hostnames"` // accept only these HOSTNAME values
+}
+
+func (s Syslog) filtered() bool {
+	return len(s.AppNames) > 0 || len(s.Hostnames) > 0
 }
 
 // Telnet holds telnet connection and safety settings.
@@ -33,7 +65,9 @@ type Telnet struct {
 
 // Policy holds policy-specific config (e.g. fps_guard).
 type Policy struct {
-	FPSGuard *FPSGuardPolicy `yaml:"fps_guard"`
+	FPSGuard                 *FPSGuardPolicy `yaml:"fps_guard"`
+	StateFile                string          `yaml:"state_file"`                  // persisted policy state (JSON); disabled when empty
+	RestoreBaselineOnStartup bool            `yaml:"restore_baseline_on_startup"` // restore at startup if the previous run left the server throttled
 }
 
 // FPSGuardPolicy config for FPS guardrail.
@@ -53,7 +87,7 @@ type FPSGuardPolicy struct {
 // ActionsCfg holds action-related config (e.g. throttle profiles and baseline).
 type ActionsCfg struct {
 	ThrottleProfiles map[string]ThrottleProfile `yaml:"throttle_profiles"`
-	Baseline         map[string]string         `yaml:"baseline"` // pref -> value for RestoreBaseline
+	Baseline         map[string]string          `yaml:"baseline"` // pref -> value for RestoreBaseline
 }
 
 // ThrottleProfile is a named list of steps (game pref sets).
@@ -69,8 +103,64 @@ type ThrottleStep struct {
 
 // API holds HTTP API settings.
 type API struct {
-	Listen    string `yaml:"listen"`
-	AuthToken string `yaml:"auth_token"`
+	Listen     string   `yaml:"listen"`
+	AuthToken  string   `yaml:"auth_token"`  // bearer token with admin scope (read and write)
+	ReadToken  string   `yaml:"read_token"`  // bearer token with read-only scope
+	AuthExempt []string `yaml:"auth_exempt"` // read paths served to GET without a token, e.g. /metrics
+	Events     Events   `yaml:"events"`
+	TLS        TLS      `yaml:"tls"`
+
+	DisableDashboard bool `yaml:"disable_dashboard"` // do not serve the web dashboard at /ui/
+}
+
+// TLS configures HTTPS for the agent's HTTP server. Certificate, key and client CA
+// files are reloaded when they change on disk.
+type TLS struct {
+	CertFile     string `yaml:"cert_file"`      // PEM certificate (chain); enables TLS
+	KeyFile      string `yaml:"key_file"`       // PEM private key
+	ClientCAFile string `yaml:"client_ca_file"` // PEM CAs for client certificates (mTLS)
+	ClientAuth   string `yaml:"client_auth"`    // require (default) or optional; needs client_ca_file
+	MinVersion   string `yaml:"min_version"`    // 1.2 (default) or 1.3
+}
+
+// Enabled reports whether TLS is configured.
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Events configures the server-sent events stream (GET /api/v1/events).
+type Events struct {
+	BufferSize       int     `yaml:"buffer_size"`       // events queued per client before it is disconnected; default 64
+	MaxClients       int     `yaml:"max_clients"`       // concurrent streams; default 16
+	HeartbeatSeconds float64 `yaml:"heartbeat_seconds"` // keep-alive comment interval; default 15
+}
+
+// AuthEnabled reports whether the API requires a bearer token.
+func (a API) AuthEnabled() bool {
+	return a.AuthToken != "" || a.ReadToken != ""
+}
+
+// Unprotected reports whether anyone who can reach the API may use it: no token, no
+// required client certificate, and a listen address that is not loopback. Optional
+// client certificates don't gate anything, so only client_auth "require" counts.
+func (a API) Unprotected() bool {
+	if a.AuthEnabled() || (a.TLS.ClientCAFile != "" && a.TLS.ClientAuth == "require") {
+		return false
+	}
+	return !isLoopback(a.Listen)
+}
+
+// isLoopback reports whether listen binds only to a loopback address.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 // Metrics holds metrics exposition settings.
@@ -79,6 +169,83 @@ type Metrics struct {
 	Path   string `yaml:"path"`
 }
 
+// History sizes the in-memory snapshot history used for windowed queries.
+type History struct {
+	MaxSamples int `yaml:"max_samples"` // snapshots kept; default 2880 (24h at one Time line per 30s)
+}
+
+// Health sets the thresholds of /readyz and /api/v1/health.
+type Health struct {
+	MaxLineAgeSeconds     float64 `yaml:"max_line_age_seconds"`     // log source fails without a line for this long; default 300
+	MaxSnapshotAgeSeconds float64 `yaml:"max_snapshot_age_seconds"` // parser fails without a Time line for this long; default 300
+	StartupGraceSeconds   float64 `yaml:"startup_grace_seconds"`    // no first line/snapshot yet is ok for this long; default 120
+	ApplierQueueWarn      float64 `yaml:"applier_queue_warn"`       // queue fill fraction that warns; default 0.5
+	ApplierQueueFail      float64 `yaml:"applier_queue_fail"`       // queue fill fraction that fails; default 0.9
+	TelnetOptional        bool    `yaml:"telnet_optional"`          // telnet problems warn instead of failing readiness
+}
+
+// Analysis configures derived trend signals (slopes, EWMA, leak/decline flags).
+type Analysis struct {
+	WindowMinutes     float64 `yaml:"window_minutes"`       // regression window; default 60
+	MinSamples        int     `yaml:"min_samples"`          // samples needed for a slope; default 10
+	MinR2             float64 `yaml:"min_r2"`               // fit quality needed to flag a trend; 0 means the default 0.6
+	EWMAAlpha         float64 `yaml:"ewma_alpha"`           // weight of the newest sample; default 0.2
+	LeakMBPerHour     float64 `yaml:"leak_mb_per_hour"`     // heap/RSS growth that flags a leak; default 200
+	FPSDeclinePerHour float64 `yaml:"fps_decline_per_hour"` // FPS loss per hour that flags a decline; default 10
+}
+
+// Audit configures the audit trail.
+type Audit struct {
+	RingSize int         `yaml:"ring_size"` // in-memory events; default 1024
+	File     AuditFile   `yaml:"file"`      // persistent JSONL log; disabled when path is empty
+	Sinks    []AuditSink `yaml:"sinks"`     // additional destinations, each with its own filter
+}
+
+// AuditSink configures one additional audit destination.
+type AuditSink struct {
+	Name        string       `yaml:"name"`
+	Type        string       `yaml:"type"`         // webhook, syslog, file
+	Statuses    []string     `yaml:"statuses"`     // only these statuses (empty: all)
+	ActionTypes []string     `yaml:"action_types"` // only these action types (empty: all)
+	BufferSize  int          `yaml:"buffer_size"`  // webhook/syslog: events buffered for delivery; default 256
+	MaxRetries  int          `yaml:"max_retries"`  // webhook/syslog: attempts after the first; default 3, -1 disables
+	Webhook     AuditWebhook `yaml:"webhook"`
+	Syslog      AuditSyslog  `yaml:"syslog"`
+	File        AuditFile    `yaml:"file"`
+}
+
+// AuditWebhook configures an HTTP webhook sink. Each event is POSTed as JSON.
+type AuditWebhook struct {
+	URL            string            `yaml:"url"`
+	Headers        map[string]string `yaml:"headers"`
+	TimeoutSeconds float64           `yaml:"timeout_seconds"` // per request; default 5
+}
+
+// AuditSyslog configures an RFC5424 syslog sink.
+type AuditSyslog struct {
+	Address  string `yaml:"address"`  // host:port
+	Protocol string `yaml:"protocol"` // udp (default) or tcp
+	AppName  string `yaml:"app_name"` // default "mg7d"
+	Facility int    `yaml:"facility"` // default 16 (local0)
+}
+
+// Audit statuses and action types accepted by audit sink filters.
+var (
+	AuditStatuses    = []string{"queued", "sent", "success", "failure", "dropped", "denied", "dry_run"}
+	AuditActionTypes = []string{"SetGamePref", "Say", "RestoreBaseline", "Noop", "APIAccess", "PolicyPause", "PolicyResume"}
+)
+
+// AuditFile configures a JSONL audit log with rotation.
+type AuditFile struct {
+	Path                 string  `yaml:"path"`
+	MaxSizeMB            float64 `yaml:"max_size_mb"`            // rotate at this size; default 10
+	MaxAgeHours          float64 `yaml:"max_age_hours"`          // rotate when older; 0 disables
+	MaxBackups           int     `yaml:"max_backups"`            // rotated files kept; default 5
+	Fsync                string  `yaml:"fsync"`                  // always, interval (default), never
+	FsyncIntervalSeconds float64 `yaml:"fsync_interval_seconds"` // default 1
+	HMACKey              string  `yaml:"hmac_key"`               // signs the record hash chain; plain SHA256 when empty
+}
+
 // Load reads and validates config from path.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -95,27 +262,404 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// Validate checks config and fails fast on invalid values.
+// UnknownKeys returns one message per key in data that matches no config field, e.g.
+// a misspelt "treshold_low". Load ignores such keys, so they silently keep the default.
+func UnknownKeys(data []byte) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var cfg Config
+	var te *yaml.TypeError
+	if !errors.As(dec.Decode(&cfg), &te) {
+		return nil
+	}
+	var out []string
+	for _, msg := range te.Errors {
+		if strings.Contains(msg, " not found in type ") {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// Validate checks config and fills in defaults. It reports every invalid value, not
+// just the first: the returned error joins one error per problem (see errors.Join).
 func Validate(c *Config) error {
+	var p problems
 	if len(c.Instances) == 0 {
-		return fmt.Errorf("config: at least one instance required")
+		p.add("at least one instance required")
 	}
-	for i, inst := range c.Instances {
+	names := make(map[string]bool)
+	syslogListens := make(map[string]int) // listen address -> first instance using it
+	for i := range c.Instances {
+		inst := &c.Instances[i]
 		if inst.Name == "" {
-			return fmt.Errorf("config: instances[%d].name required", i)
+			p.add("instances[%d].name required", i)
+		} else if names[inst.Name] {
+			p.add("instances[%d].name %q duplicated", i, inst.Name)
 		}
-		if inst.LogPath == "" {
-			return fmt.Errorf("config: instances[%d].log_path required", i)
+		names[inst.Name] = true
+		switch inst.Source.Type {
+		case "":
+			inst.Source.Type = "file"
+		case "file", "stdin", "fifo", "journald", "syslog":
+		default:
+			p.add("instances[%d].source.type %q invalid (file, stdin, fifo, journald, syslog)", i, inst.Source.Type)
 		}
-		if inst.Telnet.RateLimitPerSec <= 0 {
-			inst.Telnet.RateLimitPerSec = 2.0
+		switch inst.Source.Format {
+		case "":
+			inst.Source.Format = "raw"
+		case "raw", "docker":
+		default:
+			p.add("instances[%d].source.format %q invalid (raw, docker)", i, inst.Source.Format)
 		}
+		switch inst.Source.Overflow {
+		case "":
+			inst.Source.Overflow = "block"
+		case "block", "drop_oldest", "latest_time":
+		default:
+			p.add("instances[%d].source.overflow %q invalid (block, drop_oldest, latest_time)", i, inst.Source.Overflow)
+		}
+		if inst.Source.QueueSize < 0 {
+			p.add("instances[%d].source.queue_size must be >= 0", i)
+		}
+		if inst.Source.QueueSize == 0 {
+			inst.Source.QueueSize = 256
+		}
+		// journald keeps an empty path: it reads stdin unless a pipe is named.
+		if inst.Source.Path == "" && (inst.Source.Type == "file" || inst.Source.Type == "fifo") {
+			inst.Source.Path = inst.LogPath
+		}
+		if inst.Source.Type == "file" && inst.LogPath == "" {
+			p.add("instances[%d].log_path required", i)
+		}
+		if inst.Source.Type == "fifo" && inst.Source.Path == "" {
+			p.add("instances[%d].source.path required for fifo", i)
+		}
+		if inst.Source.Type == "syslog" {
+			if inst.Source.Syslog.Listen == "" {
+				p.add("instances[%d].source.syslog.listen required for syslog", i)
+			}
+			switch inst.Source.Syslog.Protocol {
+			case "":
+				inst.Source.Syslog.Protocol = "udp"
+			case "udp", "tcp", "both":
+			default:
+				p.add("instances[%d].source.syslog.protocol %q invalid (udp, tcp, both)", i, inst.Source.Syslog.Protocol)
+			}
+			// Instances on one address share a listener; without filters both get everything.
+			if j, ok := syslogListens[inst.Source.Syslog.Listen]; ok {
+				if !inst.Source.Syslog.filtered() || !c.Instances[j].Source.Syslog.filtered() {
+					p.add("instances[%d].source.syslog.listen %s is shared with instances[%d]; both need app_names or hostnames to route messages", i, inst.Source.Syslog.Listen, j)
+				}
+			} else if inst.Source.Syslog.Listen != "" {
+				syslogListens[inst.Source.Syslog.Listen] = i
+			}
+		}
+		validateTelnet(&p, i, &inst.Telnet)
+		validatePolicy(&p, i, inst)
 	}
 	if c.API.Listen == "" {
 		c.API.Listen = "127.0.0.1:9090"
 	}
+	if c.API.ReadToken != "" && c.API.ReadToken == c.API.AuthToken {
+		p.add("api.read_token must differ from api.auth_token")
+	}
+	for i, path := range c.API.AuthExempt {
+		switch {
+		case !strings.HasPrefix(path, "/"):
+			p.add("api.auth_exempt[%d] %q must be a path starting with /", i, path)
+		case strings.HasPrefix(path, "/api/v1/actions/") || strings.HasPrefix(path, "/api/v1/policies/"):
+			p.add("api.auth_exempt[%d] %q is an admin endpoint and always needs a token", i, path)
+		}
+	}
+	validateEvents(&p, &c.API.Events)
+	validateTLS(&p, &c.API.TLS)
 	if c.Metrics.Path == "" {
 		c.Metrics.Path = "/metrics"
 	}
-	return nil
+	if c.History.MaxSamples < 0 {
+		p.add("history.max_samples must be >= 0")
+	}
+	if c.History.MaxSamples == 0 {
+		c.History.MaxSamples = 2880
+	}
+	validateAnalysis(&p, &c.Analysis)
+	validateHealth(&p, &c.Health)
+	if c.Audit.RingSize <= 0 {
+		c.Audit.RingSize = 1024
+	}
+	validateAuditFile(&p, "audit.file", &c.Audit.File)
+	sinks := make(map[string]bool)
+	for i := range c.Audit.Sinks {
+		validateAuditSink(&p, i, &c.Audit.Sinks[i], sinks)
+	}
+	return p.err()
+}
+
+// problems collects the errors found by Validate.
+type problems []error
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Errorf("config: "+format, args...))
+}
+
+func (p problems) err() error {
+	return errors.Join(p...)
+}
+
+// validateTelnet checks and defaults instances[i].telnet. The agent only connects
+// when both host and port are set.
+func validateTelnet(p *problems, i int, t *Telnet) {
+	if t.Port < 0 || t.Port > 65535 {
+		p.add("instances[%d].telnet.port %d invalid (1-65535)", i, t.Port)
+	}
+	if (t.Host == "") != (t.Port == 0) {
+		p.add("instances[%d].telnet.host and telnet.port must be set together", i)
+	}
+	if t.RateLimitPerSec <= 0 {
+		t.RateLimitPerSec = 2.0
+	}
+}
+
+// validatePolicy checks instances[i].policy against the instance's throttle profiles.
+// FPS guard sample defaults are applied by the policy package (policy.FPSGuardDefaults).
+func validatePolicy(p *problems, i int, inst *Instance) {
+	g := inst.Policy.FPSGuard
+	if g == nil || !g.Enabled {
+		return
+	}
+	name := fmt.Sprintf("instances[%d].policy.fps_guard", i)
+	if g.ThresholdRestore < g.ThresholdLow {
+		p.add("%s.threshold_restore must be >= threshold_low", name)
+	}
+	if g.RequireLowSamples < 0 || g.SampleWindowSamples < 0 || g.RestoreStableSeconds < 0 || g.CooldownSeconds < 0 {
+		p.add("%s sample counts and seconds must be >= 0", name)
+	}
+	if g.RequireLowSamples > 0 && g.SampleWindowSamples > 0 && g.RequireLowSamples > g.SampleWindowSamples {
+		p.add("%s.require_low_samples must be <= sample_window_samples", name)
+	}
+	profile, ok := inst.Actions.ThrottleProfiles[g.ThrottleProfile]
+	switch {
+	case g.ThrottleProfile == "":
+		p.add("%s.throttle_profile required", name)
+	case !ok:
+		p.add("%s.throttle_profile %q not found in actions.throttle_profiles", name, g.ThrottleProfile)
+	case len(profile.Steps) == 0:
+		p.add("instances[%d].actions.throttle_profiles.%s has no steps", i, g.ThrottleProfile)
+	}
+	for j, s := range profile.Steps {
+		if s.Pref == "" {
+			p.add("instances[%d].actions.throttle_profiles.%s.steps[%d].pref required", i, g.ThrottleProfile, j)
+		}
+	}
+}
+
+// validateEvents checks and defaults api.events.
+func validateEvents(p *problems, e *Events) {
+	if e.BufferSize < 0 || e.MaxClients < 0 || e.HeartbeatSeconds < 0 {
+		p.add("api.events values must be >= 0")
+	}
+	if e.BufferSize == 0 {
+		e.BufferSize = 64
+	}
+	if e.MaxClients == 0 {
+		e.MaxClients = 16
+	}
+	if e.HeartbeatSeconds == 0 {
+		e.HeartbeatSeconds = 15
+	}
+}
+
+// validateTLS checks and defaults api.tls.
+func validateTLS(p *problems, t *TLS) {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		p.add("api.tls.cert_file and api.tls.key_file must be set together")
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		p.add("api.tls.client_ca_file requires cert_file and key_file")
+	}
+	switch t.ClientAuth {
+	case "":
+		t.ClientAuth = "require"
+	case "require", "optional":
+	default:
+		p.add("api.tls.client_auth %q invalid (require, optional)", t.ClientAuth)
+	}
+	switch t.MinVersion {
+	case "":
+		t.MinVersion = "1.2"
+	case "1.2", "1.3":
+	default:
+		p.add("api.tls.min_version %q invalid (1.2, 1.3)", t.MinVersion)
+	}
+}
+
+// validateAuditSink checks and defaults audit.sinks[i]; names collects sink names.
+func validateAuditSink(p *problems, i int, s *AuditSink, names map[string]bool) {
+	if s.Name == "" {
+		p.add("audit.sinks[%d].name required", i)
+	} else if names[s.Name] {
+		p.add("audit.sinks[%d].name %q duplicated", i, s.Name)
+	}
+	names[s.Name] = true
+	for _, st := range s.Statuses {
+		if !contains(AuditStatuses, st) {
+			p.add("audit.sinks[%d].statuses: %q invalid (%s)", i, st, strings.Join(AuditStatuses, ", "))
+		}
+	}
+	for _, t := range s.ActionTypes {
+		if !contains(AuditActionTypes, t) {
+			p.add("audit.sinks[%d].action_types: %q invalid (%s)", i, t, strings.Join(AuditActionTypes, ", "))
+		}
+	}
+	if s.BufferSize < 0 {
+		p.add("audit.sinks[%d].buffer_size must be >= 0", i)
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 256
+	}
+	if s.MaxRetries < -1 {
+		p.add("audit.sinks[%d].max_retries must be >= -1", i)
+	}
+	if s.MaxRetries == 0 {
+		s.MaxRetries = 3
+	}
+	switch s.Type {
+	case "webhook":
+		u, err := url.Parse(s.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("audit.sinks[%d].webhook.url must be an http(s) URL", i)
+		}
+		if s.Webhook.TimeoutSeconds < 0 {
+			p.add("audit.sinks[%d].webhook.timeout_seconds must be >= 0", i)
+		}
+		if s.Webhook.TimeoutSeconds == 0 {
+			s.Webhook.TimeoutSeconds = 5
+		}
+	case "syslog":
+		if s.Syslog.Address == "" {
+			p.add("audit.sinks[%d].syslog.address required", i)
+		}
+		switch s.Syslog.Protocol {
+		case "":
+			s.Syslog.Protocol = "udp"
+		case "udp", "tcp":
+		default:
+			p.add("audit.sinks[%d].syslog.protocol %q invalid (udp, tcp)", i, s.Syslog.Protocol)
+		}
+		if s.Syslog.AppName == "" {
+			s.Syslog.AppName = "mg7d"
+		}
+		if s.Syslog.Facility < 0 || s.Syslog.Facility > 23 {
+			p.add("audit.sinks[%d].syslog.facility must be 0-23", i)
+		}
+		if s.Syslog.Facility == 0 {
+			s.Syslog.Facility = 16
+		}
+	case "file":
+		if s.File.Path == "" {
+			p.add("audit.sinks[%d].file.path required", i)
+		}
+		validateAuditFile(p, fmt.Sprintf("audit.sinks[%d].file", i), &s.File)
+	default:
+		p.add("audit.sinks[%d].type %q invalid (webhook, syslog, file)", i, s.Type)
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHealth checks and defaults the health section.
+func validateHealth(p *problems, h *Health) {
+	if h.MaxLineAgeSeconds < 0 || h.MaxSnapshotAgeSeconds < 0 || h.StartupGraceSeconds < 0 {
+		p.add("health values must be >= 0")
+	}
+	if h.ApplierQueueWarn < 0 || h.ApplierQueueWarn > 1 || h.ApplierQueueFail < 0 || h.ApplierQueueFail > 1 {
+		p.add("health.applier_queue_warn and applier_queue_fail must be between 0 and 1")
+	}
+	if h.MaxLineAgeSeconds == 0 {
+		h.MaxLineAgeSeconds = 300
+	}
+	if h.MaxSnapshotAgeSeconds == 0 {
+		h.MaxSnapshotAgeSeconds = 300
+	}
+	if h.StartupGraceSeconds == 0 {
+		h.StartupGraceSeconds = 120
+	}
+	if h.ApplierQueueWarn == 0 {
+		h.ApplierQueueWarn = 0.5
+	}
+	if h.ApplierQueueFail == 0 {
+		h.ApplierQueueFail = 0.9
+	}
+	if h.ApplierQueueWarn > h.ApplierQueueFail {
+		p.add("health.applier_queue_warn must be <= applier_queue_fail")
+	}
+}
+
+// validateAnalysis checks and defaults the analysis section. Zero means unset, so the
+// values left after it are the ones the analyzer uses.
+func validateAnalysis(p *problems, a *Analysis) {
+	if a.WindowMinutes < 0 || a.MinSamples < 0 || a.LeakMBPerHour < 0 || a.FPSDeclinePerHour < 0 {
+		p.add("analysis values must be >= 0")
+	}
+	if a.MinSamples == 1 {
+		p.add("analysis.min_samples must be at least 2 (a slope needs two samples)")
+	}
+	if a.MinR2 < 0 || a.MinR2 > 1 {
+		p.add("analysis.min_r2 must be between 0 and 1")
+	}
+	if a.EWMAAlpha < 0 || a.EWMAAlpha > 1 {
+		p.add("analysis.ewma_alpha must be between 0 and 1")
+	}
+	if a.WindowMinutes == 0 {
+		a.WindowMinutes = 60
+	}
+	if a.MinSamples == 0 {
+		a.MinSamples = 10
+	}
+	if a.MinR2 == 0 {
+		a.MinR2 = 0.6
+	}
+	if a.EWMAAlpha == 0 {
+		a.EWMAAlpha = 0.2
+	}
+	if a.LeakMBPerHour == 0 {
+		a.LeakMBPerHour = 200
+	}
+	if a.FPSDeclinePerHour == 0 {
+		a.FPSDeclinePerHour = 10
+	}
+}
+
+// validateAuditFile checks and defaults one audit file config; name is its config path.
+func validateAuditFile(p *problems, name string, f *AuditFile) {
+	if f.Path == "" {
+		return
+	}
+	if f.MaxSizeMB < 0 || f.MaxAgeHours < 0 || f.MaxBackups < 0 || f.FsyncIntervalSeconds < 0 {
+		p.add("%s limits must be >= 0", name)
+	}
+	if f.MaxSizeMB == 0 {
+		f.MaxSizeMB = 10
+	}
+	if f.MaxBackups == 0 {
+		f.MaxBackups = 5
+	}
+	switch f.Fsync {
+	case "":
+		f.Fsync = "interval"
+	case "always", "interval", "never":
+	default:
+		p.add("%s.fsync %q invalid (always, interval, never)", name, f.Fsync)
+	}
+	if f.FsyncIntervalSeconds == 0 {
+		f.FsyncIntervalSeconds = 1
+	}
 }
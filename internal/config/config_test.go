@@ -0,0 +1,151 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := &Config{
+		Instances: []Instance{
+			{Name: "main", Telnet: Telnet{Host: "127.0.0.1"}},
+			{Name: "main", LogPath: "/x", Policy: Policy{FPSGuard: &FPSGuardPolicy{Enabled: true, ThresholdLow: 30, ThresholdRestore: 20}}},
+		},
+		API: API{TLS: TLS{MinVersion: "1.1"}},
+	}
+	err := Validate(c)
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Validate = %v, want joined errors", err)
+	}
+	var got []string
+	for _, e := range j.Unwrap() {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		"config: instances[0].log_path required",
+		"config: instances[0].telnet.host and telnet.port must be set together",
+		`config: instances[1].name "main" duplicated`,
+		"config: instances[1].policy.fps_guard.threshold_restore must be >= threshold_low",
+		"config: instances[1].policy.fps_guard.throttle_profile required",
+		`config: api.tls.min_version "1.1" invalid (1.2, 1.3)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// Defaults are still filled in around the problems.
+	if c.Instances[0].Telnet.RateLimitPerSec != 2 || c.API.Listen != "127.0.0.1:9090" {
+		t.Errorf("defaults not applied: %+v %+v", c.Instances[0].Telnet, c.API)
+	}
+}
+
+func TestValidateOK(t *testing.T) {
+	c := &Config{Instances: []Instance{{Name: "main", LogPath: "/x"}}}
+	if err := Validate(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Instances[0].Source.Type != "file" || c.Audit.RingSize != 1024 {
+		t.Errorf("defaults not applied: %+v %+v", c.Instances[0].Source, c.Audit)
+	}
+}
+
+func TestValidateSourcePathDefault(t *testing.T) {
+	c := &Config{Instances: []Instance{
+		{Name: "fifo", LogPath: "/run/7dtd.pipe", Source: LogSource{Type: "fifo"}},
+		{Name: "journal", LogPath: "/x", Source: LogSource{Type: "journald"}},
+	}}
+	if err := Validate(c); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Instances[0].Source.Path; got != "/run/7dtd.pipe" {
+		t.Errorf("fifo path = %q, want log_path", got)
+	}
+	if got := c.Instances[1].Source.Path; got != "" {
+		t.Errorf("journald path = %q, want empty (stdin)", got)
+	}
+}
+
+func TestValidateAuthExempt(t *testing.T) {
+	c := &Config{
+		Instances: []Instance{{Name: "main", LogPath: "/x"}},
+		API:       API{AuthExempt: []string{"/healthz", "metrics", "/api/v1/actions/say", "/api/v1/policies/fps_guard/pause"}},
+	}
+	err := Validate(c)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{`"metrics" must be a path`, `"/api/v1/actions/say" is an admin endpoint`, `"/api/v1/policies/fps_guard/pause" is an admin endpoint`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "/healthz") {
+		t.Errorf("/healthz rejected: %v", err)
+	}
+}
+
+func TestValidateAnalysis(t *testing.T) {
+	c := &Config{Instances: []Instance{{Name: "main", LogPath: "/x"}}, Analysis: Analysis{MinSamples: 1}}
+	if err := Validate(c); err == nil || !strings.Contains(err.Error(), "analysis.min_samples must be at least 2") {
+		t.Errorf("min_samples 1: %v", err)
+	}
+	// Validate leaves the values the analyzer uses.
+	c = &Config{Instances: []Instance{{Name: "main", LogPath: "/x"}}, Analysis: Analysis{MinSamples: 2, MinR2: 0.01}}
+	if err := Validate(c); err != nil {
+		t.Fatal(err)
+	}
+	if a := c.Analysis; a.MinSamples != 2 || a.MinR2 != 0.01 || a.WindowMinutes != 60 {
+		t.Errorf("analysis = %+v", a)
+	}
+}
+
+func TestAPIUnprotected(t *testing.T) {
+	for _, tc := range []struct {
+		api  API
+		want bool
+	}{
+		{API{Listen: "0.0.0.0:9090"}, true},
+		{API{Listen: "127.0.0.1:9090"}, false},
+		{API{Listen: "localhost:9090"}, false},
+		{API{Listen: ":9090", ReadToken: "r"}, false},
+		{API{Listen: ":9090", TLS: TLS{ClientCAFile: "ca.pem", ClientAuth: "require"}}, false},
+		{API{Listen: ":9090", TLS: TLS{ClientCAFile: "ca.pem", ClientAuth: "optional"}}, true},
+	} {
+		if got := tc.api.Unprotected(); got != tc.want {
+			t.Errorf("%+v: Unprotected = %v, want %v", tc.api, got, tc.want)
+		}
+	}
+}
+
+func TestValidateSharedSyslogListen(t *testing.T) {
+	syslog := func(name string, apps ...string) Instance {
+		return Instance{Name: name, Source: LogSource{Type: "syslog", Syslog: Syslog{Listen: ":5514", AppNames: apps}}}
+	}
+	c := &Config{Instances: []Instance{syslog("main", "7dtd-main"), syslog("event", "7dtd-event")}}
+	if err := Validate(c); err != nil {
+		t.Errorf("filtered instances sharing a listener: %v", err)
+	}
+	c = &Config{Instances: []Instance{syslog("main", "7dtd-main"), syslog("event")}}
+	if err := Validate(c); err == nil || !strings.Contains(err.Error(), "instances[1].source.syslog.listen :5514 is shared with instances[0]") {
+		t.Errorf("unfiltered instance sharing a listener: %v", err)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	data := []byte(`instances:
+  - name: main
+    log_path: /x
+    policy:
+      fps_guard:
+        treshold_low: 20
+apii:
+  listen: ":9090"
+`)
+	got := UnknownKeys(data)
+	if len(got) != 2 || !strings.Contains(got[0], "line 6: field treshold_low not found") || !strings.Contains(got[1], "field apii not found") {
+		t.Errorf("UnknownKeys = %q", got)
+	}
+	if got := UnknownKeys([]byte("instances: []\n")); len(got) != 0 {
+		t.Errorf("UnknownKeys(valid) = %q", got)
+	}
+}
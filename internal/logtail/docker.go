@@ -0,0 +1,84 @@
+package logtail
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Line formats understood by the file and stream sources (Options.Format).
+const (
+	FormatRaw    = "raw"    // one log line per line (default)
+	FormatDocker = "docker" // Docker json-file driver records
+)
+
+// lineDecoder turns raw lines of a structured format into Lines. decode reports false
+// for records that do not (yet) complete a line; reset drops state carried between
+// records when the input is reopened.
+type lineDecoder interface {
+	decode(raw []byte) (Line, bool)
+	reset()
+}
+
+// decoderFunc adapts a stateless decode function to lineDecoder.
+type decoderFunc func(raw []byte) (Line, bool)
+
+func (f decoderFunc) decode(raw []byte) (Line, bool) { return f(raw) }
+func (decoderFunc) reset()                           {}
+
+// newDecoder returns the raw-line decoder for format, or nil for plain text.
+func newDecoder(format string, maxLineBytes int) lineDecoder {
+	switch format {
+	case FormatDocker:
+		return &dockerDecoder{max: maxLineBytes}
+	default:
+		return nil
+	}
+}
+
+// dockerRecord is one line of a Docker json-file log
+// (/var/lib/docker/containers/<id>/<id>-json.log).
+type dockerRecord struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// dockerDecoder unwraps json-file records. Docker splits log lines longer than 16KB
+// into several records; only the last one ends in "\n", so chunks are buffered until
+// then. Line.Time is the container timestamp of the first chunk.
+type dockerDecoder struct {
+	max     int
+	pending strings.Builder
+	first   time.Time
+}
+
+func (d *dockerDecoder) decode(raw []byte) (Line, bool) {
+	var rec dockerRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return Line{}, false
+	}
+	if d.pending.Len() == 0 {
+		d.first, _ = time.Parse(time.RFC3339Nano, rec.Time)
+	}
+	complete := strings.HasSuffix(rec.Log, "\n")
+	if room := d.max - d.pending.Len(); room > 0 {
+		chunk := strings.TrimSuffix(rec.Log, "\n")
+		if len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		d.pending.WriteString(chunk)
+	}
+	if !complete {
+		return Line{}, false
+	}
+	line := Line{Text: strings.TrimSuffix(d.pending.String(), "\r"), Time: d.first}
+	d.reset()
+	return line, true
+}
+
+// reset drops a partially reassembled line.
+func (d *dockerDecoder) reset() {
+	d.pending.Reset()
+	d.first = time.Time{}
+}
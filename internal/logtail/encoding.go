@@ -0,0 +1,159 @@
+package logtail
+
+import (
+	"bytes"
+	"sync/atomic"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings detected by normalizer.
+const (
+	encUnknown = iota
+	encUTF8
+	encUTF16LE
+	encUTF16BE
+)
+
+// detectBytes is how much input the normalizer looks at before settling on an encoding
+// when there is no BOM.
+const detectBytes = 64
+
+// normalizer turns the raw byte stream of a log into UTF-8: it strips a UTF-8 BOM,
+// transcodes UTF-16LE/BE (by BOM, or by the zero-byte pattern of ASCII text), and per
+// line removes a trailing CR and repairs invalid UTF-8. Windows-built servers and some
+// hosting panels write such logs.
+type normalizer struct {
+	enc      int
+	pending  []byte // undecided prefix, odd UTF-16 byte or split surrogate pair
+	repaired atomic.Uint64
+}
+
+// transcode returns p (plus any held-back bytes) converted to UTF-8. Bytes that cannot
+// be converted yet are kept for the next call.
+func (n *normalizer) transcode(p []byte) []byte {
+	if n.enc == encUTF8 && len(n.pending) == 0 {
+		return p
+	}
+	buf := append(n.pending, p...)
+	n.pending = nil
+	if n.enc == encUnknown {
+		if !n.detect(buf) {
+			n.pending = buf
+			return nil
+		}
+		switch {
+		case bytes.HasPrefix(buf, []byte{0xEF, 0xBB, 0xBF}):
+			buf = buf[3:]
+		case bytes.HasPrefix(buf, []byte{0xFF, 0xFE}), bytes.HasPrefix(buf, []byte{0xFE, 0xFF}):
+			buf = buf[2:]
+		}
+	}
+	switch n.enc {
+	case encUTF16LE, encUTF16BE:
+		return n.decodeUTF16(buf)
+	default:
+		return buf
+	}
+}
+
+// detect sets n.enc from a BOM or, without one, from the first detectBytes bytes.
+// Returns false if more input is needed.
+func (n *normalizer) detect(b []byte) bool {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xEF, 0xBB, 0xBF}):
+		n.enc = encUTF8
+		return true
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}):
+		n.enc = encUTF16LE
+		return true
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		n.enc = encUTF16BE
+		return true
+	}
+	if len(b) < 3 && isBOMPrefix(b) {
+		return false
+	}
+	if len(b) < detectBytes && bytes.IndexByte(b, '\n') < 0 {
+		// Too little data to judge; wait for a full sample or the first line.
+		return false
+	}
+	sample := b
+	if len(sample) > detectBytes {
+		sample = sample[:detectBytes]
+	}
+	var evenZero, oddZero int
+	for i, c := range sample {
+		if c != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZero++
+		} else {
+			oddZero++
+		}
+	}
+	half := len(sample) / 2
+	switch {
+	case half > 0 && oddZero*2 > half && evenZero*8 < half:
+		n.enc = encUTF16LE
+	case half > 0 && evenZero*2 > half && oddZero*8 < half:
+		n.enc = encUTF16BE
+	default:
+		n.enc = encUTF8
+	}
+	return true
+}
+
+func isBOMPrefix(b []byte) bool {
+	for _, bom := range [][]byte{{0xEF, 0xBB, 0xBF}, {0xFF, 0xFE}, {0xFE, 0xFF}} {
+		if bytes.HasPrefix(bom, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeUTF16 converts complete code units of b, holding back an odd trailing byte and
+// a trailing high surrogate whose pair has not arrived yet.
+func (n *normalizer) decodeUTF16(b []byte) []byte {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if n.enc == encUTF16LE {
+			units = append(units, uint16(b[i])|uint16(b[i+1])<<8)
+		} else {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+	}
+	keep := len(b) % 2
+	if k := len(units); k > 0 && utf16.IsSurrogate(rune(units[k-1])) && units[k-1] < 0xDC00 {
+		units = units[:k-1]
+		keep += 2
+	}
+	if keep > 0 {
+		n.pending = append(n.pending, b[len(b)-keep:]...)
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
+
+// cleanLine removes a trailing CR and replaces invalid UTF-8 sequences with U+FFFD,
+// counting repaired lines.
+func (n *normalizer) cleanLine(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if !utf8.Valid(line) {
+		n.repaired.Add(1)
+		line = bytes.ToValidUTF8(line, []byte("\uFFFD"))
+	}
+	return line
+}
+
+// reset forgets the detected encoding (a reopened file may differ). The repair counter
+// is kept.
+func (n *normalizer) reset() {
+	n.enc = encUnknown
+	n.pending = nil
+}
@@ -0,0 +1,80 @@
+package logtail
+
+import (
+	"context"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	var out []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return out
+}
+
+// collect runs input through a pipeline in chunks of size step and returns the lines.
+func collect(t *testing.T, input []byte, step int) ([]string, Stats) {
+	t.Helper()
+	p := newPipeline(Options{MaxLineBytes: defaultMaxLineBytes})
+	ctx := context.Background()
+	for i := 0; i < len(input); i += step {
+		end := i + step
+		if end > len(input) {
+			end = len(input)
+		}
+		if err := p.write(ctx, input[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.queue.close()
+	var got []string
+	for l := range p.queue.ch {
+		got = append(got, l.Text)
+	}
+	return got, p.stats()
+}
+
+func TestNormalizer_Encodings(t *testing.T) {
+	text := "Time: 1 FPS: 30 \U0001F600\r\nzweite Zeile ü\r\n"
+	want := []string{"Time: 1 FPS: 30 \U0001F600", "zweite Zeile ü"}
+	cases := []struct {
+		name  string
+		input []byte
+	}{
+		{"utf8", []byte(text)},
+		{"utf8 bom", append([]byte{0xEF, 0xBB, 0xBF}, text...)},
+		{"utf16le bom", utf16Bytes(text, false, true)},
+		{"utf16be bom", utf16Bytes(text, true, true)},
+		{"utf16le no bom", utf16Bytes(text, false, false)},
+		{"utf16be no bom", utf16Bytes(text, true, false)},
+	}
+	for _, tc := range cases {
+		// Odd chunk sizes split BOMs, code units and surrogate pairs across writes.
+		for _, step := range []int{1, 3, 4096} {
+			got, _ := collect(t, tc.input, step)
+			if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Errorf("%s (step %d): got %q, want %q", tc.name, step, got, want)
+			}
+		}
+	}
+}
+
+func TestNormalizer_RepairsInvalidUTF8(t *testing.T) {
+	got, st := collect(t, []byte("ok\nbad \xff\xfe byte\n"), 4096)
+	if len(got) != 2 || got[1] != "bad � byte" {
+		t.Errorf("got %q", got)
+	}
+	if st.Repaired != 1 {
+		t.Errorf("repaired: got %d, want 1", st.Repaired)
+	}
+}
@@ -34,7 +34,7 @@ func TestReplayFixture(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		for line := range tailer.Lines() {
-			lines = append(lines, line)
+			lines = append(lines, line.Text)
 		}
 		close(done)
 	}()
@@ -48,7 +48,7 @@ func TestReplayFixture(t *testing.T) {
 		t.Errorf("expected at least 10 lines from fixture, got %d", len(lines))
 	}
 	// First line should start with "Time:"
-	if len(lines) > 0 && len(lines[0]) > 4 && lines[0][:4] != "Time:" {
+	if len(lines) > 0 && len(lines[0]) > 5 && lines[0][:5] != "Time:" {
 		t.Errorf("first line should be Time line: %q", lines[0])
 	}
 }
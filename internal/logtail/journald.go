@@ -0,0 +1,98 @@
+package logtail
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// journaldRecordFactor sizes the raw record limit relative to MaxLineBytes: a
+// non-UTF-8 MESSAGE is exported as a byte array of up to 4 characters per byte, and
+// the record carries other fields besides.
+const journaldRecordFactor = 16
+
+// NewJournaldSource creates a source reading `journalctl -o json -f` output from the
+// named pipe at path, or from stdin when path is empty or "-". Each JSON record's
+// MESSAGE becomes one line, capped at MaxLineBytes; __REALTIME_TIMESTAMP becomes
+// Line.Time. Records that cannot be decoded, such as ones cut at the raw record limit,
+// are counted in Stats.Dropped.
+func NewJournaldSource(path string, opts Options) (*StreamSource, error) {
+	var s *StreamSource
+	if path == "" || path == "-" {
+		s = NewStdinSource(opts)
+	} else {
+		var err error
+		if s, err = NewFIFOSource(path, opts); err != nil {
+			return nil, err
+		}
+	}
+	// Split at the record limit so MESSAGE is capped after decoding, not the JSON.
+	s.lines.splitter.max = journaldRecordFactor * s.opts.MaxLineBytes
+	s.lines.decoder = journaldDecoder{max: s.opts.MaxLineBytes, dropped: &s.lines.queue.dropped}
+	return s, nil
+}
+
+// journaldDecoder decodes journal records, caps their MESSAGE at max bytes and counts
+// undecodable records in dropped.
+type journaldDecoder struct {
+	max     int
+	dropped *atomic.Uint64
+}
+
+func (d journaldDecoder) decode(raw []byte) (Line, bool) {
+	line, ok, err := decodeJournald(raw)
+	if err != nil {
+		d.dropped.Add(1)
+		return Line{}, false
+	}
+	if len(line.Text) > d.max {
+		cut := d.max
+		for cut > 0 && !utf8.RuneStart(line.Text[cut]) {
+			cut--
+		}
+		line.Text = line.Text[:cut]
+	}
+	return line, ok
+}
+
+func (journaldDecoder) reset() {}
+
+// journaldRecord holds the journal export fields we use. MESSAGE is a string, or an
+// array of bytes when the message is not valid UTF-8.
+type journaldRecord struct {
+	Message  json.RawMessage `json:"MESSAGE"`
+	Realtime string          `json:"__REALTIME_TIMESTAMP"`
+}
+
+// decodeJournald turns one JSON record into a Line. Records without a MESSAGE are
+// skipped (ok=false); invalid records and MESSAGEs return an error.
+func decodeJournald(raw []byte) (Line, bool, error) {
+	var rec journaldRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return Line{}, false, err
+	}
+	if len(rec.Message) == 0 {
+		return Line{}, false, nil
+	}
+	var msg string
+	if err := json.Unmarshal(rec.Message, &msg); err != nil {
+		var ints []int
+		if err := json.Unmarshal(rec.Message, &ints); err != nil {
+			return Line{}, false, errors.New("logtail: journald MESSAGE is neither a string nor bytes")
+		}
+		b := make([]byte, len(ints))
+		for i, v := range ints {
+			b[i] = byte(v)
+		}
+		msg = string(b)
+	}
+	line := Line{Text: strings.TrimRight(msg, "\r\n")}
+	if us, err := strconv.ParseInt(rec.Realtime, 10, 64); err == nil {
+		line.Time = time.UnixMicro(us)
+	}
+	return line, true, nil
+}
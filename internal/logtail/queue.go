@@ -0,0 +1,135 @@
+package logtail
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow behaviours for a full line channel (Options.Overflow).
+const (
+	OverflowBlock      = "block"       // stop reading until the consumer catches up (default)
+	OverflowDropOldest = "drop_oldest" // evict the oldest queued line
+	OverflowLatestTime = "latest_time" // discard non-Time lines and keep only the newest Time lines
+)
+
+const defaultQueueSize = 256
+
+// Stats is a point-in-time view of a source's line queue and input normalisation.
+type Stats struct {
+	Dropped    uint64 // lines discarded by the overflow policy, and undecodable journald records
+	Repaired   uint64 // lines with invalid UTF-8 that were repaired
+	Queued     int    // lines waiting to be consumed
+	Capacity   int
+	Open       bool      // input currently open (file, pipe or listeners)
+	Offset     int64     // bytes consumed from the current input; the file position for the tailer
+	LastLineAt time.Time // when the most recent line was queued; zero if none yet
+}
+
+// lineQueue is the bounded channel between a source and its consumer. It has a single
+// producer (the source's Run goroutine), so eviction never races with another send.
+type lineQueue struct {
+	ch       chan Line
+	overflow string
+	dropped  atomic.Uint64
+	lastLine atomic.Int64 // unix nanos of the last queued line
+	mu       sync.Mutex
+	closed   bool
+}
+
+func newLineQueue(size int, overflow string) *lineQueue {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	return &lineQueue{ch: make(chan Line, size), overflow: overflow}
+}
+
+// send delivers line according to the overflow policy. Only OverflowBlock waits; it
+// returns ctx.Err() if ctx is cancelled first.
+func (q *lineQueue) send(ctx context.Context, line Line) error {
+	if line.ReadAt.IsZero() {
+		line.ReadAt = time.Now()
+	}
+	q.lastLine.Store(line.ReadAt.UnixNano())
+	switch q.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.ch <- line:
+				return nil
+			default:
+			}
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	case OverflowLatestTime:
+		select {
+		case q.ch <- line:
+			return nil
+		default:
+		}
+		if !isTimeLine(line.Text) {
+			q.dropped.Add(1)
+			return nil
+		}
+		q.compactTimeLines()
+		return q.send(ctx, line)
+	default:
+		return sendLine(ctx, q.ch, line)
+	}
+}
+
+// compactTimeLines drains the queue and puts back only Time lines, leaving room for at
+// least one more line. Order is preserved; the oldest Time lines are dropped first.
+func (q *lineQueue) compactTimeLines() {
+	var keep []Line
+	for drained := false; !drained; {
+		select {
+		case l := <-q.ch:
+			if isTimeLine(l.Text) {
+				keep = append(keep, l)
+			} else {
+				q.dropped.Add(1)
+			}
+		default:
+			drained = true
+		}
+	}
+	if excess := len(keep) - (cap(q.ch) - 1); excess > 0 {
+		q.dropped.Add(uint64(excess))
+		keep = keep[excess:]
+	}
+	for _, l := range keep {
+		q.ch <- l
+	}
+}
+
+func (q *lineQueue) stats() Stats {
+	st := Stats{
+		Dropped:  q.dropped.Load(),
+		Queued:   len(q.ch),
+		Capacity: cap(q.ch),
+	}
+	if n := q.lastLine.Load(); n != 0 {
+		st.LastLineAt = time.Unix(0, n)
+	}
+	return st
+}
+
+func (q *lineQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}
+
+func isTimeLine(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "Time:")
+}
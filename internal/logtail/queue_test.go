@@ -0,0 +1,72 @@
+package logtail
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func drain(q *lineQueue) []string {
+	var out []string
+	for {
+		select {
+		case l := <-q.ch:
+			out = append(out, l.Text)
+		default:
+			return out
+		}
+	}
+}
+
+func TestLineQueue_DropOldest(t *testing.T) {
+	q := newLineQueue(2, OverflowDropOldest)
+	ctx := context.Background()
+	for _, s := range []string{"a", "b", "c"} {
+		if err := q.send(ctx, Line{Text: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := q.stats(); st.Dropped != 1 || st.Queued != 2 || st.Capacity != 2 {
+		t.Errorf("stats: %+v", st)
+	}
+	if got := drain(q); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("got %v, want [b c]", got)
+	}
+}
+
+func TestLineQueue_LatestTime(t *testing.T) {
+	q := newLineQueue(3, OverflowLatestTime)
+	ctx := context.Background()
+	for _, s := range []string{"Time: 1", "noise", "Time: 2", "more noise", "Time: 3", "Time: 4"} {
+		if err := q.send(ctx, Line{Text: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := drain(q)
+	want := []string{"Time: 2", "Time: 3", "Time: 4"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+	if st := q.stats(); st.Dropped != 3 {
+		t.Errorf("dropped: got %d, want 3", st.Dropped)
+	}
+}
+
+func TestLineQueue_BlockRespectsContext(t *testing.T) {
+	q := newLineQueue(1, OverflowBlock)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = q.send(ctx, Line{Text: "a"})
+	if err := q.send(ctx, Line{Text: "b"}); err == nil {
+		t.Error("expected context error when full")
+	}
+	if st := q.stats(); st.Dropped != 0 {
+		t.Errorf("block mode should not drop: %+v", st)
+	}
+}
@@ -0,0 +1,57 @@
+package logtail
+
+import (
+	"errors"
+	"io"
+)
+
+// ReadLines runs r through the same decoding as the sources (transcoding, line
+// splitting, UTF-8 repair and opts.Format) and calls fn for each line in order until
+// EOF or fn returns an error. A final line without a newline is included. Replay uses
+// it to feed a recorded log to the parser.
+func ReadLines(r io.Reader, opts Options, fn func(Line) error) error {
+	if opts.MaxLineBytes <= 0 {
+		opts.MaxLineBytes = defaultMaxLineBytes
+	}
+	var norm normalizer
+	split := lineSplitter{max: opts.MaxLineBytes}
+	decoder := newDecoder(opts.Format, opts.MaxLineBytes)
+	emit := func(raw []byte) error {
+		raw = norm.cleanLine(raw)
+		line := Line{Text: string(raw)}
+		if decoder != nil {
+			var ok bool
+			if line, ok = decoder.decode(raw); !ok {
+				return nil
+			}
+		}
+		return fn(line)
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if ferr := split.feed(norm.transcode(buf[:n]), emit); ferr != nil {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Input shorter than detectBytes is still undecided: it is UTF-8 text.
+	if norm.enc == encUnknown && len(norm.pending) > 0 {
+		norm.enc = encUTF8
+		if err := split.feed(norm.transcode(nil), emit); err != nil {
+			return err
+		}
+	}
+	if len(split.partial) > 0 {
+		return emit(split.partial)
+	}
+	return nil
+}
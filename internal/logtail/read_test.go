@@ -0,0 +1,41 @@
+package logtail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLines(t *testing.T) {
+	var got []string
+	err := ReadLines(strings.NewReader("a\r\nb\nc"), Options{}, func(l Line) error {
+		got = append(got, l.Text)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "|") != "a|b|c" {
+		t.Errorf("lines = %q", got)
+	}
+}
+
+func TestReadLinesDocker(t *testing.T) {
+	in := `{"log":"Time: 1 FPS: 30\n","stream":"stdout","time":"2024-05-01T12:00:00.5Z"}` + "\n" +
+		`not json` + "\n" +
+		`{"log":"Time: 2 FPS: 31\n","stream":"stdout","time":"2024-05-01T12:00:30Z"}` + "\n"
+	var got []Line
+	err := ReadLines(strings.NewReader(in), Options{Format: FormatDocker}, func(l Line) error {
+		got = append(got, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Text != "Time: 1 FPS: 30" {
+		t.Fatalf("lines = %+v", got)
+	}
+	if want := time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC); !got[1].Time.Equal(want) {
+		t.Errorf("time = %v, want %v", got[1].Time, want)
+	}
+}
@@ -0,0 +1,168 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Source types selectable per instance (config instances[].source.type).
+const (
+	SourceFile     = "file"
+	SourceStdin    = "stdin"
+	SourceFIFO     = "fifo"
+	SourceJournald = "journald"
+)
+
+// Line is one complete log line plus metadata supplied by the source.
+type Line struct {
+	Text   string
+	Time   time.Time // timestamp from the source (e.g. journald); zero if the source has none
+	ReadAt time.Time // when the agent read the line; stands in for Time when measuring lag
+}
+
+// Source produces complete log lines. Lines() must be consumed to avoid blocking;
+// the channel is closed when Run returns.
+type Source interface {
+	Lines() <-chan Line
+	Run(ctx context.Context) error
+	Stats() Stats
+}
+
+// NewSource creates the source named by kind. path is the log file for "file", the named
+// pipe for "fifo" and an optional pipe for "journald" (empty or "-" reads stdin). The
+// "syslog" source ignores path and uses opts.Syslog.
+func NewSource(kind, path string, opts Options) (Source, error) {
+	switch kind {
+	case "", SourceFile:
+		return NewTailer(path, opts)
+	case SourceStdin:
+		return NewStdinSource(opts), nil
+	case SourceFIFO:
+		return NewFIFOSource(path, opts)
+	case SourceJournald:
+		return NewJournaldSource(path, opts)
+	case SourceSyslog:
+		return NewSyslogSource(opts.Syslog, opts)
+	default:
+		return nil, fmt.Errorf("logtail: unknown source type %q", kind)
+	}
+}
+
+// pipeline turns raw source bytes into queued Lines:
+// transcode to UTF-8 -> split lines -> strip CR / repair UTF-8 -> decode format -> queue.
+type pipeline struct {
+	norm     normalizer
+	splitter lineSplitter
+	decoder  lineDecoder // nil: raw line text
+	queue    *lineQueue
+	offset   atomic.Int64
+	open     atomic.Bool
+}
+
+func newPipeline(opts Options) *pipeline {
+	return &pipeline{
+		splitter: lineSplitter{max: opts.MaxLineBytes},
+		decoder:  newDecoder(opts.Format, opts.MaxLineBytes),
+		queue:    newLineQueue(opts.QueueSize, opts.Overflow),
+	}
+}
+
+// write feeds a chunk of raw input; complete lines are queued.
+func (p *pipeline) write(ctx context.Context, b []byte) error {
+	p.offset.Add(int64(len(b)))
+	return p.splitter.feed(p.norm.transcode(b), func(raw []byte) error {
+		raw = p.norm.cleanLine(raw)
+		line := Line{Text: string(raw)}
+		if p.decoder != nil {
+			var ok bool
+			if line, ok = p.decoder.decode(raw); !ok {
+				return nil
+			}
+		}
+		return p.queue.send(ctx, line)
+	})
+}
+
+// reset drops buffered input when the underlying stream is reopened.
+func (p *pipeline) reset() {
+	p.norm.reset()
+	p.splitter.reset()
+	if p.decoder != nil {
+		p.decoder.reset()
+	}
+}
+
+// begin marks a (re)opened input positioned at offset and drops partial-line state.
+func (p *pipeline) begin(offset int64) {
+	p.reset()
+	p.offset.Store(offset)
+	p.open.Store(true)
+}
+
+// end marks the input closed.
+func (p *pipeline) end() {
+	p.open.Store(false)
+}
+
+func (p *pipeline) stats() Stats {
+	st := p.queue.stats()
+	st.Repaired = p.norm.repaired.Load()
+	st.Open = p.open.Load()
+	st.Offset = p.offset.Load()
+	return st
+}
+
+// lineSplitter accumulates raw bytes and yields complete lines, truncating lines longer
+// than max. Shared by all sources so partial-line handling is identical.
+type lineSplitter struct {
+	partial []byte
+	max     int
+}
+
+// feed appends p and calls emit for each complete line (without the trailing '\n').
+// A partial line longer than max is emitted in max-sized pieces.
+func (s *lineSplitter) feed(p []byte, emit func([]byte) error) error {
+	s.partial = append(s.partial, p...)
+	for {
+		idx := 0
+		for idx < len(s.partial) && s.partial[idx] != '\n' {
+			idx++
+		}
+		if idx < len(s.partial) {
+			line := s.partial[:idx]
+			s.partial = s.partial[idx+1:]
+			if len(line) > s.max {
+				line = line[:s.max]
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(s.partial) > s.max {
+			if err := emit(s.partial[:s.max]); err != nil {
+				return err
+			}
+			s.partial = s.partial[s.max:]
+			continue
+		}
+		return nil
+	}
+}
+
+// reset discards any buffered partial line (e.g. after the file was rotated).
+func (s *lineSplitter) reset() {
+	s.partial = nil
+}
+
+// sendLine delivers line on ch or returns ctx.Err() if ctx is cancelled first.
+func sendLine(ctx context.Context, ch chan<- Line, line Line) error {
+	select {
+	case ch <- line:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,188 @@
+package logtail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineSplitter_MaxLineBytes(t *testing.T) {
+	s := lineSplitter{max: 4}
+	var got []string
+	emit := func(b []byte) error {
+		got = append(got, string(b))
+		return nil
+	}
+	_ = s.feed([]byte("ab\nabcdefgh"), emit)
+	_ = s.feed([]byte("ij\n"), emit)
+	// Overlong partial lines are flushed in max-sized pieces; the final piece is truncated.
+	want := []string{"ab", "abcd", "efgh"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeJournald(t *testing.T) {
+	line, ok, _ := decodeJournald([]byte(`{"__REALTIME_TIMESTAMP":"1700000000123456","MESSAGE":"Time: 1 FPS: 30\r\n","_SYSTEMD_UNIT":"7dtd.service"}`))
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if line.Text != "Time: 1 FPS: 30" {
+		t.Errorf("text: %q", line.Text)
+	}
+	if want := time.UnixMicro(1700000000123456); !line.Time.Equal(want) {
+		t.Errorf("time: got %v, want %v", line.Time, want)
+	}
+
+	// Non-UTF-8 messages are exported as byte arrays.
+	line, ok, _ = decodeJournald([]byte(`{"MESSAGE":[84,105,109,101,58]}`))
+	if !ok || line.Text != "Time:" {
+		t.Errorf("byte-array message: ok=%v text=%q", ok, line.Text)
+	}
+	if !line.Time.IsZero() {
+		t.Errorf("expected zero time without __REALTIME_TIMESTAMP, got %v", line.Time)
+	}
+
+	for _, raw := range []string{`{"PRIORITY":"6"}`, `not json`, `{"MESSAGE":{"a":1}}`} {
+		if _, ok, _ := decodeJournald([]byte(raw)); ok {
+			t.Errorf("expected skip for %s", raw)
+		}
+	}
+}
+
+func TestJournaldOversizedRecord(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = old })
+	s, err := NewJournaldSource("", Options{MaxLineBytes: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first record is longer than MaxLineBytes but within the 256-byte record
+	// limit: decoded, then its MESSAGE is capped. The second exceeds the record limit
+	// and is counted, not decoded.
+	big := strings.Repeat("x", 300)
+	go func() {
+		_, _ = w.WriteString(`{"MESSAGE":"Time: 1 FPS: 30 Heap: 100 RSS: 200"}` + "\n" +
+			`{"MESSAGE":"` + big + `"}` + "\n")
+		w.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() { _ = s.Run(ctx) }()
+
+	var got []string
+	for line := range s.Lines() {
+		got = append(got, line.Text)
+	}
+	if len(got) != 1 || got[0] != "Time: 1 FPS: 30 " {
+		t.Errorf("lines = %q", got)
+	}
+	if st := s.Stats(); st.Dropped == 0 {
+		t.Errorf("oversized record not counted: %+v", st)
+	}
+}
+
+func TestDockerDecoder_PartialChunks(t *testing.T) {
+	d := &dockerDecoder{max: defaultMaxLineBytes}
+	if _, ok := d.decode([]byte(`{"log":"Time: 1 FPS: ","stream":"stdout","time":"2024-05-01T10:00:00.5Z"}`)); ok {
+		t.Fatal("partial chunk should not produce a line")
+	}
+	line, ok := d.decode([]byte(`{"log":"30\r\n","stream":"stdout","time":"2024-05-01T10:00:01Z"}`))
+	if !ok {
+		t.Fatal("expected reassembled line")
+	}
+	if line.Text != "Time: 1 FPS: 30" {
+		t.Errorf("text: %q", line.Text)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 5e8, time.UTC); !line.Time.Equal(want) {
+		t.Errorf("time should be the first chunk's: got %v, want %v", line.Time, want)
+	}
+
+	line, ok = d.decode([]byte(`{"log":"next\n","stream":"stderr","time":"2024-05-01T10:00:02Z"}`))
+	if !ok || line.Text != "next" {
+		t.Errorf("after reassembly: ok=%v text=%q", ok, line.Text)
+	}
+	if _, ok := d.decode([]byte(`garbage`)); ok {
+		t.Error("invalid record should be skipped")
+	}
+}
+
+func TestTailerDockerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c-json.log")
+	data := `{"log":"Time: 0 FPS: 50\n","stream":"stdout","time":"2024-05-01T10:00:00Z"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tailer, err := NewTailer(path, Options{FromBeginning: true, PollInterval: 20 * time.Millisecond, Format: FormatDocker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() { _ = tailer.Run(ctx) }()
+
+	select {
+	case line := <-tailer.Lines():
+		if line.Text != "Time: 0 FPS: 50" || line.Time.IsZero() {
+			t.Errorf("got %+v", line)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for line")
+	}
+}
+
+func TestTailerDockerRotationDropsPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c-json.log")
+	// The old file ends in the middle of a split line.
+	data := `{"log":"Time: 0 FPS: 50\n","stream":"stdout","time":"2024-05-01T10:00:00Z"}` + "\n" +
+		`{"log":"Time: 1 FPS: ","stream":"stdout","time":"2024-05-01T10:00:01Z"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tailer, err := NewTailer(path, Options{FromBeginning: true, PollInterval: 20 * time.Millisecond, Format: FormatDocker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go func() { _ = tailer.Run(ctx) }()
+
+	next := func() Line {
+		t.Helper()
+		select {
+		case line := <-tailer.Lines():
+			return line
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for line")
+			return Line{}
+		}
+	}
+	if line := next(); line.Text != "Time: 0 FPS: 50" {
+		t.Fatalf("first line: %q", line.Text)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	data = `{"log":"Time: 2 FPS: 40\n","stream":"stdout","time":"2024-05-01T10:00:02Z"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if line := next(); line.Text != "Time: 2 FPS: 40" {
+		t.Errorf("line after rotation joined to the old fragment: %q", line.Text)
+	}
+}
@@ -0,0 +1,123 @@
+package logtail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// StreamSource reads lines from a byte stream: stdin or a named pipe (FIFO).
+// A FIFO is reopened with backoff if it is missing; stdin ends the source at EOF.
+type StreamSource struct {
+	path  string // empty for stdin
+	opts  Options
+	lines *pipeline
+}
+
+// NewStdinSource creates a source reading the agent's standard input.
+func NewStdinSource(opts Options) *StreamSource {
+	return newStreamSource("", opts)
+}
+
+// NewFIFOSource creates a source reading the named pipe at path. The pipe must already
+// exist (e.g. created with mkfifo); the agent keeps it open across writer restarts.
+func NewFIFOSource(path string, opts Options) (*StreamSource, error) {
+	if path == "" {
+		return nil, errors.New("logtail: fifo source requires a path")
+	}
+	return newStreamSource(path, opts), nil
+}
+
+func newStreamSource(path string, opts Options) *StreamSource {
+	if opts.PollInterval == 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.MaxLineBytes <= 0 {
+		opts.MaxLineBytes = defaultMaxLineBytes
+	}
+	return &StreamSource{
+		path:  path,
+		opts:  opts,
+		lines: newPipeline(opts),
+	}
+}
+
+// Lines returns the channel of complete log lines. Closed when the source stops.
+func (s *StreamSource) Lines() <-chan Line {
+	return s.lines.queue.ch
+}
+
+// Stats returns line queue occupancy, overflow drops and repaired lines.
+func (s *StreamSource) Stats() Stats {
+	return s.lines.stats()
+}
+
+// Run reads until ctx is cancelled (or stdin reaches EOF).
+func (s *StreamSource) Run(ctx context.Context) error {
+	defer s.lines.queue.close()
+	if s.path == "" {
+		err := s.readFrom(ctx, os.Stdin)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	backoff := 100 * time.Millisecond
+	const maxBackoff = 5 * time.Second
+	for ctx.Err() == nil {
+		// O_RDWR keeps the pipe open without a writer: open does not block and
+		// reads do not hit EOF when the game process restarts.
+		if f, err := os.OpenFile(s.path, os.O_RDWR, 0); err == nil {
+			backoff = 100 * time.Millisecond
+			// A read error (pipe removed, I/O error) falls through to reopen.
+			_ = s.readFrom(ctx, f)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if backoff < maxBackoff {
+			backoff *= 2
+		}
+	}
+	return ctx.Err()
+}
+
+// readFrom copies lines from r until error or ctx is done. r is closed on return and
+// on cancellation, which unblocks a pending Read.
+func (s *StreamSource) readFrom(ctx context.Context, r io.ReadCloser) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = r.Close()
+		case <-stop:
+		}
+	}()
+	defer r.Close()
+
+	s.lines.begin(0)
+	defer s.lines.end()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if ferr := s.lines.write(ctx, buf[:n]); ferr != nil {
+				return ferr
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package logtail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceSyslog receives game output forwarded by rsyslog or similar (config source.type).
+const SourceSyslog = "syslog"
+
+// SyslogOptions configures the syslog receiver.
+type SyslogOptions struct {
+	Listen    string   // address for UDP and/or TCP, e.g. ":5514"
+	Protocol  string   // "udp", "tcp" or "both" (default "udp")
+	AppNames  []string // accept only these APP-NAME/TAG values (empty: any)
+	Hostnames []string // accept only these HOSTNAME values (empty: any)
+}
+
+// syslogMessage is the part of an RFC5424/RFC3164 message the agent uses.
+type syslogMessage struct {
+	Time     time.Time
+	Hostname string
+	AppName  string
+	Msg      string
+}
+
+// SyslogSource is a built-in RFC5424/RFC3164 receiver over UDP and TCP. Messages that
+// pass the app-name/hostname filters become lines for the instance's parser. Sources
+// with the same listen address and protocol share one listener, so a single rsyslog
+// forwarder can feed several instances: each message goes to every source whose
+// filters accept it.
+type SyslogSource struct {
+	sopts  SyslogOptions
+	opts   Options
+	lines  *pipeline
+	sendMu sync.Mutex // receivers run concurrently; the line queue expects one producer
+
+	addrMu  sync.Mutex
+	udpAddr net.Addr
+	tcpAddr net.Addr
+}
+
+// NewSyslogSource creates a syslog receiver. Call Run to start listening.
+func NewSyslogSource(sopts SyslogOptions, opts Options) (*SyslogSource, error) {
+	if sopts.Listen == "" {
+		return nil, errors.New("logtail: syslog source requires a listen address")
+	}
+	switch sopts.Protocol {
+	case "":
+		sopts.Protocol = "udp"
+	case "udp", "tcp", "both":
+	default:
+		return nil, fmt.Errorf("logtail: syslog protocol %q invalid (udp, tcp, both)", sopts.Protocol)
+	}
+	if opts.MaxLineBytes <= 0 {
+		opts.MaxLineBytes = defaultMaxLineBytes
+	}
+	return &SyslogSource{sopts: sopts, opts: opts, lines: newPipeline(opts)}, nil
+}
+
+// Lines returns the channel of received log lines. Closed when the source stops.
+func (s *SyslogSource) Lines() <-chan Line {
+	return s.lines.queue.ch
+}
+
+// Stats returns line queue occupancy, overflow drops and repaired lines.
+func (s *SyslogSource) Stats() Stats {
+	return s.lines.stats()
+}
+
+// Addr returns the bound address for network ("udp" or "tcp"), or nil before Run has
+// started listening on it. Useful with a ":0" listen address.
+func (s *SyslogSource) Addr(network string) net.Addr {
+	s.addrMu.Lock()
+	defer s.addrMu.Unlock()
+	if network == "tcp" {
+		return s.tcpAddr
+	}
+	return s.udpAddr
+}
+
+// Run listens until ctx is cancelled, joining the listener of any running source with
+// the same address. Listener errors at startup are returned.
+func (s *SyslogSource) Run(ctx context.Context) error {
+	defer s.lines.queue.close()
+	var networks []string
+	if s.sopts.Protocol == "udp" || s.sopts.Protocol == "both" {
+		networks = append(networks, "udp")
+	}
+	if s.sopts.Protocol == "tcp" || s.sopts.Protocol == "both" {
+		networks = append(networks, "tcp")
+	}
+	var joined []*syslogReceiver
+	leave := func() {
+		for _, r := range joined {
+			r.leave(s)
+		}
+	}
+	for _, network := range networks {
+		r, err := joinSyslogReceiver(ctx, network, s.sopts.Listen, s)
+		if err != nil {
+			leave()
+			return err
+		}
+		joined = append(joined, r)
+		s.addrMu.Lock()
+		if network == "tcp" {
+			s.tcpAddr = r.addr
+		} else {
+			s.udpAddr = r.addr
+		}
+		s.addrMu.Unlock()
+	}
+
+	s.lines.begin(0)
+	defer s.lines.end()
+	<-ctx.Done()
+	// Leaving waits for deliveries to this source to finish before Lines() closes.
+	leave()
+	return ctx.Err()
+}
+
+// deliver queues the lines of a message that passed the source's filters. raw is the
+// received size, counted in the source's offset.
+func (s *SyslogSource) deliver(ctx context.Context, msg syslogMessage, raw int) {
+	s.lines.offset.Add(int64(raw))
+	if !s.accept(msg) {
+		return
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	for _, text := range strings.Split(msg.Msg, "\n") {
+		text = string(s.lines.norm.cleanLine([]byte(text)))
+		if text == "" {
+			continue
+		}
+		if err := s.lines.queue.send(ctx, Line{Text: text, Time: msg.Time}); err != nil {
+			return
+		}
+	}
+}
+
+func (s *SyslogSource) accept(m syslogMessage) bool {
+	return matchAny(s.sopts.AppNames, m.AppName) && matchAny(s.sopts.Hostnames, m.Hostname)
+}
+
+// syslogReceivers holds the running listeners by network and listen address.
+var (
+	syslogReceiversMu sync.Mutex
+	syslogReceivers   = make(map[string]*syslogReceiver)
+)
+
+// syslogReceiver is one UDP or TCP listener shared by the sources that listen on its
+// address. It closes when the last source leaves.
+type syslogReceiver struct {
+	key     string // in syslogReceivers
+	addr    net.Addr
+	maxLine int
+	pc      net.PacketConn
+	ln      net.Listener
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu   sync.RWMutex // held for reading while delivering, so leave waits for deliveries
+	subs map[*SyslogSource]context.Context
+}
+
+// joinSyslogReceiver adds s, with the context its lines are sent under, to the
+// receiver for network and listen, starting one if none is running. Addresses with
+// port 0 are registered under the port they were given.
+func joinSyslogReceiver(ctx context.Context, network, listen string, s *SyslogSource) (*syslogReceiver, error) {
+	syslogReceiversMu.Lock()
+	defer syslogReceiversMu.Unlock()
+	if r := syslogReceivers[network+" "+listen]; r != nil {
+		r.mu.Lock()
+		r.subs[s] = ctx
+		r.mu.Unlock()
+		return r, nil
+	}
+	r := &syslogReceiver{maxLine: s.opts.MaxLineBytes, subs: map[*SyslogSource]context.Context{s: ctx}}
+	var err error
+	if network == "tcp" {
+		if r.ln, err = net.Listen("tcp", listen); err != nil {
+			return nil, err
+		}
+		r.addr = r.ln.Addr()
+	} else {
+		if r.pc, err = net.ListenPacket("udp", listen); err != nil {
+			return nil, err
+		}
+		r.addr = r.pc.LocalAddr()
+	}
+	r.key = network + " " + listen
+	if _, port, _ := net.SplitHostPort(listen); port == "0" {
+		r.key = network + " " + r.addr.String()
+	}
+	syslogReceivers[r.key] = r
+
+	rctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if r.ln != nil {
+			r.serveTCP(rctx)
+		} else {
+			r.serveUDP()
+		}
+	}()
+	return r, nil
+}
+
+// leave removes s and closes the listener if it was the last source.
+func (r *syslogReceiver) leave(s *SyslogSource) {
+	syslogReceiversMu.Lock()
+	r.mu.Lock()
+	delete(r.subs, s)
+	last := len(r.subs) == 0
+	r.mu.Unlock()
+	if last {
+		delete(syslogReceivers, r.key)
+	}
+	syslogReceiversMu.Unlock()
+	if !last {
+		return
+	}
+	// Closing the listeners unblocks the receivers; wait for them before returning.
+	r.cancel()
+	if r.pc != nil {
+		_ = r.pc.Close()
+	}
+	if r.ln != nil {
+		_ = r.ln.Close()
+	}
+	r.wg.Wait()
+}
+
+func (r *syslogReceiver) serveUDP() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := r.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		r.handle(buf[:n])
+	}
+}
+
+func (r *syslogReceiver) serveTCP(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := r.ln.Accept()
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+			defer stop()
+			defer conn.Close()
+			r.readFrames(ctx, conn)
+		}()
+	}
+}
+
+// readFrames reads RFC6587 frames: octet-counted ("LEN SP MSG") or newline-delimited.
+func (r *syslogReceiver) readFrames(ctx context.Context, rd io.Reader) {
+	br := bufio.NewReaderSize(rd, 32*1024)
+	split := lineSplitter{max: r.maxLine}
+	midLine := false // inside a newline-delimited frame longer than the read buffer
+	for ctx.Err() == nil {
+		first, err := br.Peek(1)
+		if err != nil {
+			return
+		}
+		if !midLine && first[0] >= '1' && first[0] <= '9' {
+			lenStr, err := br.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+			if err != nil || n <= 0 || n > r.maxLine {
+				return
+			}
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(br, frame); err != nil {
+				return
+			}
+			r.handle(frame)
+			continue
+		}
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			_ = split.feed(line, func(frame []byte) error {
+				r.handle(frame)
+				return nil
+			})
+		}
+		midLine = errors.Is(err, bufio.ErrBufferFull)
+		if err != nil && !midLine {
+			return
+		}
+	}
+}
+
+// handle parses one syslog message and hands it to every source.
+func (r *syslogReceiver) handle(raw []byte) {
+	msg, ok := parseSyslog(bytes.TrimRight(raw, "\r\n\x00"))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for s, ctx := range r.subs {
+		if !ok {
+			s.lines.offset.Add(int64(len(raw)))
+			continue
+		}
+		s.deliver(ctx, msg, len(raw))
+	}
+}
+
+func matchAny(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, want := range list {
+		if strings.EqualFold(want, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSyslog parses an RFC5424 or RFC3164 message. The PRI part is required.
+func parseSyslog(b []byte) (syslogMessage, bool) {
+	s := string(b)
+	if !strings.HasPrefix(s, "<") {
+		return syslogMessage{}, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return syslogMessage{}, false
+	}
+	if _, err := strconv.Atoi(s[1:end]); err != nil {
+		return syslogMessage{}, false
+	}
+	s = s[end+1:]
+	if strings.HasPrefix(s, "1 ") {
+		return parseRFC5424(s[2:])
+	}
+	return parseRFC3164(s), true
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]".
+func parseRFC5424(s string) (syslogMessage, bool) {
+	fields := strings.SplitN(s, " ", 6)
+	if len(fields) < 6 {
+		// No MSG and no trailing space after SD.
+		if len(fields) != 5 {
+			return syslogMessage{}, false
+		}
+		fields = append(fields, "")
+	}
+	var m syslogMessage
+	if fields[0] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return syslogMessage{}, false
+		}
+		m.Time = t
+	}
+	m.Hostname = nilValue(fields[1])
+	m.AppName = nilValue(fields[2])
+	m.Msg = skipStructuredData(fields[5])
+	m.Msg = strings.TrimPrefix(m.Msg, "\ufeff") // RFC5424 UTF-8 BOM
+	return m, true
+}
+
+// skipStructuredData drops the SD field ("-" or one or more "[...]" elements).
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(strings.TrimPrefix(s, "-"), " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		i := 1
+		for i < len(s) && s[i] != ']' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+func nilValue(v string) string {
+	if v == "-" {
+		return ""
+	}
+	return v
+}
+
+// parseRFC3164 parses "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG". Missing parts are
+// tolerated; the timestamp has no year, so the current year is assumed.
+func parseRFC3164(s string) syslogMessage {
+	var m syslogMessage
+	if len(s) >= 16 && s[15] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, s[:15], time.Local); err == nil {
+			now := time.Now()
+			m.Time = t.AddDate(now.Year(), 0, 0)
+			if m.Time.After(now.Add(24 * time.Hour)) {
+				m.Time = m.Time.AddDate(-1, 0, 0) // December message received in January
+			}
+			s = s[16:]
+			if sp := strings.IndexByte(s, ' '); sp > 0 {
+				m.Hostname = s[:sp]
+				s = s[sp+1:]
+			}
+		}
+	}
+	if colon := strings.Index(s, ": "); colon > 0 && !strings.ContainsAny(s[:colon], " ") {
+		tag := s[:colon]
+		if i := strings.IndexByte(tag, '['); i > 0 {
+			tag = tag[:i]
+		}
+		m.AppName = tag
+		s = s[colon+2:]
+	}
+	m.Msg = s
+	return m
+}
@@ -0,0 +1,171 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseSyslog_RFC5424(t *testing.T) {
+	raw := `<14>1 2024-05-01T10:00:00.5Z game01 7dtd 1234 - [meta a="x\]y"] Time: 1 FPS: 30`
+	m, ok := parseSyslog([]byte(raw))
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if m.Hostname != "game01" || m.AppName != "7dtd" || m.Msg != "Time: 1 FPS: 30" {
+		t.Errorf("got %+v", m)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 5e8, time.UTC); !m.Time.Equal(want) {
+		t.Errorf("time: got %v, want %v", m.Time, want)
+	}
+
+	m, ok = parseSyslog([]byte("<14>1 - - - - - -"))
+	if !ok || m.Msg != "" || !m.Time.IsZero() {
+		t.Errorf("nil values: ok=%v %+v", ok, m)
+	}
+}
+
+func TestParseSyslog_RFC3164(t *testing.T) {
+	m, ok := parseSyslog([]byte("<13>May  1 10:00:00 game01 7dtd[1234]: Time: 1 FPS: 30"))
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if m.Hostname != "game01" || m.AppName != "7dtd" || m.Msg != "Time: 1 FPS: 30" {
+		t.Errorf("got %+v", m)
+	}
+	if m.Time.Month() != time.May || m.Time.Day() != 1 || m.Time.Year() < 2024 {
+		t.Errorf("time: %v", m.Time)
+	}
+
+	if _, ok := parseSyslog([]byte("no pri")); ok {
+		t.Error("message without PRI should be rejected")
+	}
+}
+
+// startSyslog runs a syslog source on a kernel-assigned port and returns the bound
+// address for network.
+func startSyslog(t *testing.T, ctx context.Context, sopts SyslogOptions, network string) (*SyslogSource, <-chan error, string) {
+	t.Helper()
+	sopts.Listen = "127.0.0.1:0"
+	sopts.Protocol = network
+	src, err := NewSyslogSource(sopts, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx) }()
+	for i := 0; i < 100; i++ {
+		if addr := src.Addr(network); addr != nil {
+			return src, done, addr.String()
+		}
+		select {
+		case err := <-done:
+			t.Skip("no listener:", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("syslog source did not start listening")
+	return nil, nil, ""
+}
+
+// expectLines waits until every wanted line arrived; any other line fails the test.
+func expectLines(t *testing.T, ctx context.Context, src *SyslogSource, lines ...string) {
+	t.Helper()
+	want := make(map[string]bool)
+	for _, l := range lines {
+		want[l] = true
+	}
+	for len(want) > 0 {
+		select {
+		case l := <-src.Lines():
+			if !want[l.Text] {
+				t.Fatalf("unexpected line %q", l.Text)
+			}
+			delete(want, l.Text)
+		case <-ctx.Done():
+			t.Fatalf("timeout; still waiting for %v", want)
+		}
+	}
+}
+
+func TestSyslogSource_TCPFilterAndFraming(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	src, done, addr := startSyslog(t, ctx, SyslogOptions{AppNames: []string{"7dtd"}}, "tcp")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := "<14>1 - host other - - - Time: 9 FPS: 1"
+	counted := "<14>1 - host 7dtd - - - Time: 2 FPS: 20"
+	fmt.Fprintf(conn, "<13>May  1 10:00:00 host 7dtd: Time: 1 FPS: 10\n%s\n%d %s", other, len(counted), counted)
+	_ = conn.Close()
+
+	expectLines(t, ctx, src, "Time: 1 FPS: 10", "Time: 2 FPS: 20")
+	cancel()
+	<-done
+	if _, ok := <-src.Lines(); ok {
+		t.Error("Lines() should be closed after Run returns")
+	}
+}
+
+func TestSyslogSource_UDPHostnameFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	src, done, addr := startSyslog(t, ctx, SyslogOptions{Hostnames: []string{"game01"}}, "udp")
+
+	udp, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = udp.Write([]byte("<14>1 - game02 7dtd - - - Time: 9 FPS: 1"))
+	_, _ = udp.Write([]byte("<14>1 - game01 7dtd - - - Time: 3 FPS: 30"))
+	_ = udp.Close()
+
+	expectLines(t, ctx, src, "Time: 3 FPS: 30")
+	cancel()
+	<-done
+}
+
+func TestSyslogSource_SharedListenerRoutesByFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	mainCtx, stopMain := context.WithCancel(ctx)
+	mainSrc, mainDone, addr := startSyslog(t, mainCtx, SyslogOptions{AppNames: []string{"7dtd-main"}}, "udp")
+
+	// A second instance on the same address joins the running listener.
+	event, err := NewSyslogSource(SyslogOptions{Listen: addr, AppNames: []string{"7dtd-event"}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventDone := make(chan error, 1)
+	go func() { eventDone <- event.Run(ctx) }()
+	for event.Addr("udp") == nil {
+		select {
+		case err := <-eventDone:
+			t.Fatalf("second source: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	udp, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	_, _ = udp.Write([]byte("<14>1 - host 7dtd-main - - - Time: 1 FPS: 10"))
+	_, _ = udp.Write([]byte("<14>1 - host 7dtd-event - - - Time: 2 FPS: 20"))
+	expectLines(t, ctx, mainSrc, "Time: 1 FPS: 10")
+	expectLines(t, ctx, event, "Time: 2 FPS: 20")
+
+	// The listener stays up for the remaining source.
+	stopMain()
+	<-mainDone
+	_, _ = udp.Write([]byte("<14>1 - host 7dtd-event - - - Time: 3 FPS: 30"))
+	expectLines(t, ctx, event, "Time: 3 FPS: 30")
+	cancel()
+	<-eventDone
+}
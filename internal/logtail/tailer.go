@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -14,9 +13,13 @@ import (
 
 // Options configures the tailer.
 type Options struct {
-	PollInterval   time.Duration // when no fs events; default 1s
-	MaxLineBytes   int           // max line size before truncating; default 64k
-	FromBeginning  bool          // if true, read from start (for tests)
+	PollInterval  time.Duration // when no fs events; default 1s
+	MaxLineBytes  int           // max line size before truncating; default 64k
+	FromBeginning bool          // if true, read from start (for tests)
+	Format        string        // line format: "raw" (default) or "docker" (json-file records)
+	QueueSize     int           // line channel capacity; default 256
+	Overflow      string        // full-channel behaviour: "block" (default), "drop_oldest", "latest_time"
+	Syslog        SyslogOptions // listener and filters for the "syslog" source
 }
 
 const (
@@ -24,14 +27,13 @@ const (
 	defaultMaxLineBytes = 64 * 1024
 )
 
-// Tailer follows a log file and emits complete lines on a channel.
+// Tailer follows a log file and emits complete lines on a channel. It is the "file" Source.
 // Rotation-safe: handles copytruncate and rename+recreate. Partial-line safe.
 type Tailer struct {
-	path    string
-	opts    Options
-	linesCh chan string
-	mu      sync.Mutex
-	closed  bool
+	path     string
+	opts     Options
+	lines    *pipeline
+	reopened bool
 }
 
 // NewTailer creates a tailer for path. Lines() must be consumed to avoid blocking.
@@ -47,15 +49,21 @@ func NewTailer(path string, opts Options) (*Tailer, error) {
 		return nil, err
 	}
 	return &Tailer{
-		path:    abs,
-		opts:    opts,
-		linesCh: make(chan string, 256),
+		path:  abs,
+		opts:  opts,
+		lines: newPipeline(opts),
 	}, nil
 }
 
 // Lines returns the channel of complete log lines. Closed when tailer stops.
-func (t *Tailer) Lines() <-chan string {
-	return t.linesCh
+func (t *Tailer) Lines() <-chan Line {
+	return t.lines.queue.ch
+}
+
+// Stats returns line queue occupancy, overflow drops, repaired lines and the read
+// position in the current file.
+func (t *Tailer) Stats() Stats {
+	return t.lines.stats()
 }
 
 // Run runs the tailer until ctx is cancelled. Survives rotation and temporary missing file.
@@ -72,7 +80,7 @@ func (t *Tailer) Run(ctx context.Context) error {
 	}
 
 	var (
-		backoff   = time.Millisecond * 100
+		backoff    = time.Millisecond * 100
 		maxBackoff = time.Second * 5
 		pollTicker *time.Ticker
 	)
@@ -82,8 +90,9 @@ func (t *Tailer) Run(ctx context.Context) error {
 	}
 
 	for {
-		// Open and read until EOF; then wait for events or poll.
-		readErr := t.followFile(ctx, watcher, pollTicker, &backoff, maxBackoff)
+		// Open and read until EOF; then wait for events or poll. Errors (missing file,
+		// rotation) are handled by backing off and reopening.
+		_ = t.followFile(ctx, watcher, pollTicker, &backoff, maxBackoff)
 		if ctx.Err() != nil {
 			break
 		}
@@ -102,12 +111,7 @@ func (t *Tailer) Run(ctx context.Context) error {
 		}
 	}
 
-	t.mu.Lock()
-	if !t.closed {
-		t.closed = true
-		close(t.linesCh)
-	}
-	t.mu.Unlock()
+	t.lines.queue.close()
 	return ctx.Err()
 }
 
@@ -123,18 +127,22 @@ func (t *Tailer) followFile(ctx context.Context, watcher *fsnotify.Watcher, poll
 	if err != nil {
 		return err
 	}
+	// Skip existing content only on the first open; a file that reappears after
+	// rotation is new and is read from the start.
 	startOffset := int64(0)
-	if !t.opts.FromBeginning {
+	if !t.opts.FromBeginning && !t.reopened {
 		startOffset = info.Size()
 	}
+	t.reopened = true
 	if _, err := f.Seek(startOffset, io.SeekStart); err != nil {
 		return err
 	}
 
 	*backoff = time.Millisecond * 100
-	origInode := inodeOf(info)
+	origInfo := info
 	reader := bufio.NewReaderSize(f, 32*1024)
-	var partial []byte
+	t.lines.begin(startOffset)
+	defer t.lines.end()
 	var tickCh <-chan time.Time
 	if pollTicker != nil {
 		tickCh = pollTicker.C
@@ -144,34 +152,8 @@ func (t *Tailer) followFile(ctx context.Context, watcher *fsnotify.Watcher, poll
 		buf := make([]byte, 4096)
 		n, err := reader.Read(buf)
 		if n > 0 {
-			partial = append(partial, buf[:n]...)
-			for {
-				idx := 0
-				for idx < len(partial) && partial[idx] != '\n' {
-					idx++
-				}
-				if idx < len(partial) {
-					line := partial[:idx]
-					partial = partial[idx+1:]
-					if len(line) > t.opts.MaxLineBytes {
-						line = line[:t.opts.MaxLineBytes]
-					}
-					select {
-					case t.linesCh <- string(line):
-					case <-ctx.Done():
-						return false, ctx.Err()
-					}
-					continue
-				}
-				if len(partial) > t.opts.MaxLineBytes {
-					select {
-					case t.linesCh <- string(partial[:t.opts.MaxLineBytes]):
-					case <-ctx.Done():
-						return false, ctx.Err()
-					}
-					partial = partial[t.opts.MaxLineBytes:]
-				}
-				break
+			if ferr := t.lines.write(ctx, buf[:n]); ferr != nil {
+				return false, ferr
 			}
 		}
 		if err == io.EOF {
@@ -195,7 +177,7 @@ func (t *Tailer) followFile(ctx context.Context, watcher *fsnotify.Watcher, poll
 			// file missing
 			return statErr
 		}
-		if inodeOf(curInfo) != origInode || curInfo.Size() < info.Size() {
+		if !os.SameFile(origInfo, curInfo) || curInfo.Size() < info.Size() {
 			// rotated (new file or copytruncate)
 			return nil
 		}
@@ -226,9 +208,3 @@ func (t *Tailer) followFile(ctx context.Context, watcher *fsnotify.Watcher, poll
 		}
 	}
 }
-
-func inodeOf(info os.FileInfo) uint64 {
-	// Use ModTime+Size as a simple "identity" on Windows where inode may not exist.
-	// On Unix we could use syscall.Stat_t.Ino. For cross-platform we use size + modtime.
-	return uint64(info.Size())<<32 | uint64(info.ModTime().UnixNano()&0xffffffff)
-}
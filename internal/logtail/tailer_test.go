@@ -28,7 +28,7 @@ func TestTailerPartialLines(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		for line := range tailer.Lines() {
-			got = append(got, line)
+			got = append(got, line.Text)
 			if len(got) >= 2 {
 				break
 			}
@@ -72,7 +72,7 @@ func TestTailerPartialLineAcrossReads(t *testing.T) {
 	var got []string
 	go func() {
 		for line := range tailer.Lines() {
-			got = append(got, line)
+			got = append(got, line.Text)
 		}
 	}()
 
@@ -116,7 +116,7 @@ func TestTailerRotationSimulation(t *testing.T) {
 	var got []string
 	go func() {
 		for line := range tailer.Lines() {
-			got = append(got, line)
+			got = append(got, line.Text)
 		}
 	}()
 
@@ -152,3 +152,128 @@ func TestTailerRotationSimulation(t *testing.T) {
 		t.Errorf("expected to see after_rotation after rotation, got: %v", got)
 	}
 }
+
+// collectLines reads lines from ch until want lines arrived or the timeout expires.
+func collectLines(ch <-chan Line, want int, timeout time.Duration) []string {
+	var got []string
+	deadline := time.After(timeout)
+	for len(got) < want {
+		select {
+		case line, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, line.Text)
+		case <-deadline:
+			return got
+		}
+	}
+	return got
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailerAppendIsNotRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tailer, err := NewTailer(path, Options{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = tailer.Run(ctx) }()
+
+	time.Sleep(80 * time.Millisecond)
+	appendFile(t, path, "a\n")
+	time.Sleep(80 * time.Millisecond)
+	appendFile(t, path, "b\n")
+
+	// Growing the file changes size and mtime but not its identity: nothing is
+	// re-read and nothing is skipped.
+	got := collectLines(tailer.Lines(), 3, 500*time.Millisecond)
+	if strings.Join(got, ",") != "a,b" {
+		t.Errorf("got %v, want [a b]", got)
+	}
+}
+
+func TestTailerRenameRecreate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "game.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tailer, err := NewTailer(path, Options{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = tailer.Run(ctx) }()
+
+	time.Sleep(80 * time.Millisecond)
+	appendFile(t, path, "live\n")
+	time.Sleep(80 * time.Millisecond)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("new1\nnew2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing content is skipped only on the first open; the recreated file is
+	// read from its start.
+	got := collectLines(tailer.Lines(), 4, time.Second)
+	if strings.Join(got, ",") != "live,new1,new2" {
+		t.Errorf("got %v, want [live new1 new2]", got)
+	}
+}
+
+func TestTailerStatsPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tailer, err := NewTailer(path, Options{PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = tailer.Run(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !tailer.Stats().Open && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := tailer.Stats(); !st.Open || st.Offset != 9 || !st.LastLineAt.IsZero() {
+		t.Fatalf("after open (skips existing content): %+v", st)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("new\n")
+	_ = f.Close()
+	select {
+	case <-tailer.Lines():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for line")
+	}
+	if st := tailer.Stats(); st.Offset != 13 || st.LastLineAt.IsZero() {
+		t.Errorf("after line: %+v", st)
+	}
+}
@@ -3,7 +3,11 @@ package metrics
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/mg7d/mg7d/internal/analysis"
+	"github.com/mg7d/mg7d/internal/logtail"
 	"github.com/mg7d/mg7d/internal/state"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -20,8 +24,10 @@ type Registry struct {
 		zombies  prometheus.Gauge
 		heapMB   prometheus.Gauge
 		rssMB    prometheus.Gauge
+		tailLag  prometheus.GaugeFunc
 	}
-	mu sync.Mutex
+	lastLine atomic.Int64 // unix nanos of the newest consumed line; 0 before the first
+	mu       sync.Mutex
 }
 
 // NewRegistry creates a registry with instance label for multi-instance support.
@@ -72,6 +78,12 @@ func (r *Registry) RegisterCollectors() {
 		ConstLabels: labels,
 	})
 
+	r.gauges.tailLag = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "mg7d_tail_lag_seconds",
+		Help:        "Seconds since the newest consumed log line was written (0 before the first line).",
+		ConstLabels: labels,
+	}, func() float64 { return r.tailLag(time.Now()).Seconds() })
+
 	prometheus.MustRegister(
 		r.gauges.fps,
 		r.gauges.players,
@@ -80,9 +92,108 @@ func (r *Registry) RegisterCollectors() {
 		r.gauges.zombies,
 		r.gauges.heapMB,
 		r.gauges.rssMB,
+		r.gauges.tailLag,
+	)
+}
+
+// RegisterSource exports line queue metrics for a log source: drops caused by the
+// overflow policy, repaired lines, current occupancy and capacity. Values are read at
+// scrape time.
+func (r *Registry) RegisterSource(src logtail.Source) {
+	labels := prometheus.Labels{"instance": r.instance}
+	prometheus.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "mg7d_tail_dropped_lines_total",
+			Help:        "Log lines dropped because the line queue was full, and journald records that could not be decoded.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(src.Stats().Dropped) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "mg7d_tail_repaired_lines_total",
+			Help:        "Log lines with invalid UTF-8 that were repaired before parsing.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(src.Stats().Repaired) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "mg7d_tail_queue_length",
+			Help:        "Log lines waiting in the line queue.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(src.Stats().Queued) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "mg7d_tail_queue_capacity",
+			Help:        "Capacity of the line queue.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(src.Stats().Capacity) }),
 	)
 }
 
+// RegisterAnalyzer exports the analyzer's slopes (per hour) for the fields that have a
+// gauge, plus the leak and decline flags. Values are read at scrape time; a slope is 0
+// until the window has enough samples.
+func (r *Registry) RegisterAnalyzer(a *analysis.Analyzer) {
+	labels := prometheus.Labels{"instance": r.instance}
+	slopes := []struct {
+		field state.Field
+		help  string
+	}{
+		{state.FieldFPS, "FPS change per hour (linear regression over the analysis window)."},
+		{state.FieldHeapMB, "Heap growth in MB per hour (linear regression over the analysis window)."},
+		{state.FieldRSSMB, "RSS growth in MB per hour (linear regression over the analysis window)."},
+		{state.FieldPlayers, "Player count change per hour."},
+		{state.FieldChunks, "Chunk count change per hour."},
+		{state.FieldEntities, "Entity count change per hour."},
+		{state.FieldZombies, "Zombie count change per hour."},
+	}
+	for _, s := range slopes {
+		f := s.field
+		prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "mg7d_" + string(f) + "_slope",
+			Help:        s.help,
+			ConstLabels: labels,
+		}, func() float64 {
+			t, _ := a.Signals().Trend(f)
+			return t.SlopePerHour
+		}))
+	}
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "mg7d_memory_leak",
+			Help:        "1 if heap or RSS shows sustained growth above analysis.leak_mb_per_hour.",
+			ConstLabels: labels,
+		}, func() float64 { return boolGauge(a.Signals().MemoryLeak) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "mg7d_fps_decline",
+			Help:        "1 if FPS shows a sustained decline beyond analysis.fps_decline_per_hour.",
+			ConstLabels: labels,
+		}, func() float64 { return boolGauge(a.Signals().FPSDecline) }),
+	)
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ObserveLineTime records when the newest consumed log line was written. The tail lag
+// gauge is computed from it at scrape time, so it keeps growing while the source stalls.
+func (r *Registry) ObserveLineTime(t time.Time) {
+	n := t.UnixNano()
+	for {
+		cur := r.lastLine.Load()
+		if n <= cur || r.lastLine.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+func (r *Registry) tailLag(now time.Time) time.Duration {
+	n := r.lastLine.Load()
+	if n == 0 {
+		return 0
+	}
+	return max(now.Sub(time.Unix(0, n)), 0)
+}
+
 // UpdateFromSnapshot updates all gauges from a snapshot.
 func (r *Registry) UpdateFromSnapshot(s state.Snapshot) {
 	r.mu.Lock()
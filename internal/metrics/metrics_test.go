@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTailLag(t *testing.T) {
+	r := NewRegistry("test")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := r.tailLag(now); got != 0 {
+		t.Errorf("before any line: %v", got)
+	}
+	r.ObserveLineTime(now.Add(-5 * time.Second))
+	r.ObserveLineTime(now.Add(-time.Minute)) // older line: ignored
+	if got := r.tailLag(now); got != 5*time.Second {
+		t.Errorf("lag = %v, want 5s", got)
+	}
+	// No new lines: the lag keeps growing.
+	if got := r.tailLag(now.Add(time.Minute)); got != 65*time.Second {
+		t.Errorf("stalled lag = %v, want 65s", got)
+	}
+	r.ObserveLineTime(now.Add(time.Second)) // clock skew
+	if got := r.tailLag(now); got != 0 {
+		t.Errorf("future line time: lag = %v, want 0", got)
+	}
+}
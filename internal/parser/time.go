@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/mg7d/mg7d/internal/state"
 )
@@ -14,6 +15,13 @@ import (
 // (zero, false, err) on parse error for a line that looked like a Time line.
 // Timestamp: if the line contains a parseable time, use it; otherwise use time.Now() (monotonic at parse time).
 func ParseTimeLine(line string) (state.Snapshot, bool, error) {
+	return ParseTimeLineAt(line, time.Time{})
+}
+
+// ParseTimeLineAt is ParseTimeLine for lines that carry a source timestamp (e.g. the
+// Docker or journald record time). A non-zero sourceTime is used as Timestamp unless the
+// line itself contains a parseable time.
+func ParseTimeLineAt(line string, sourceTime time.Time) (state.Snapshot, bool, error) {
 	line = strings.TrimSpace(line)
 	if !strings.HasPrefix(line, "Time:") {
 		return state.Snapshot{}, false, nil
@@ -22,6 +30,9 @@ func ParseTimeLine(line string) (state.Snapshot, bool, error) {
 	var snap state.Snapshot
 	snap.ParsedAt = time.Now()
 	snap.Timestamp = snap.ParsedAt
+	if !sourceTime.IsZero() {
+		snap.Timestamp = sourceTime
+	}
 	snap.EntitiesActive = -1
 	snap.CGo = 0
 	snap.CGoMissing = true
@@ -82,13 +93,33 @@ func ParseTimeLine(line string) (state.Snapshot, bool, error) {
 	return snap, true, nil
 }
 
+// Uptime returns the server uptime from the leading value of a "Time:" line, which
+// 7DTD logs in minutes ("Time: 125.43m FPS: ..."; the "m" is optional). ok is false
+// for other lines and for Time lines that carry a date instead.
+func Uptime(line string) (up time.Duration, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "Time:") {
+		return 0, false
+	}
+	val, found := parseKeyValuePairs(strings.TrimSpace(strings.TrimPrefix(line, "Time:")))["Time"]
+	if !found {
+		return 0, false
+	}
+	minutes, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(val), "m"), 64)
+	if err != nil || minutes < 0 {
+		return 0, false
+	}
+	return time.Duration(minutes * float64(time.Minute)), true
+}
+
 // parseKeyValuePairs splits "val0 Key1: val1 Key2: val2" where values can contain spaces.
 // Leading value (before first " Word:") is stored as "Time". Keys are words ending with ':'.
 func parseKeyValuePairs(s string) map[string]string {
 	out := make(map[string]string)
 	s = strings.TrimSpace(s)
-	// Optional leading value before first " Word:" (e.g. "123.45 FPS: 30.5" -> Time=123.45)
-	if idx := firstKeyStart(s); idx > 0 {
+	// Optional leading value before first " Word:" (e.g. "123.45 FPS: 30.5" -> Time=123.45).
+	// Skipped when s already starts with a key ("FPS: 60 Heap: 100").
+	if idx := firstKeyStart(s); idx > 0 && !startsWithKey(s) {
 		out["Time"] = strings.TrimSpace(s[:idx])
 		s = strings.TrimSpace(s[idx:])
 	}
@@ -148,6 +179,19 @@ func firstKeyStart(s string) int {
 	return -1
 }
 
+// startsWithKey reports whether the first whitespace-delimited token of s is a key: it
+// starts with a letter and ends with ':' (so "2024-01-15T14:30:00Z" is a value).
+func startsWithKey(s string) bool {
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return false
+	}
+	k := 0
+	for k < len(s) && s[k] != ' ' && s[k] != '\t' && s[k] != ':' {
+		k++
+	}
+	return k > 0 && k < len(s) && s[k] == ':'
+}
+
 func parseMB(s string) (float64, error) {
 	s = strings.TrimSpace(strings.TrimSuffix(strings.ToLower(s), "mb"))
 	return strconv.ParseFloat(s, 64)
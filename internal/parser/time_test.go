@@ -115,6 +115,38 @@ func TestParseKeyValuePairs(t *testing.T) {
 	}
 }
 
+func TestParseKeyValuePairs_LeadingValue(t *testing.T) {
+	m := parseKeyValuePairs("123.45 FPS: 30.5 Heap: 100")
+	if m["Time"] != "123.45" || m["FPS"] != "30.5" {
+		t.Errorf("leading value: %v", m)
+	}
+	// A line that starts with a key has no leading value to split off.
+	m = parseKeyValuePairs("Ply: 4 Zom: 120")
+	if _, ok := m["Time"]; ok || m["Ply"] != "4" || m["Zom"] != "120" {
+		t.Errorf("leading key: %v", m)
+	}
+}
+
+func TestUptime(t *testing.T) {
+	for line, want := range map[string]time.Duration{
+		"Time: 125.50m FPS: 30 Heap: 100": 125*time.Minute + 30*time.Second,
+		"Time: 2 FPS: 30":                 2 * time.Minute,
+	} {
+		if got, ok := Uptime(line); !ok || got != want {
+			t.Errorf("%q: %v %v, want %v", line, got, ok, want)
+		}
+	}
+	for _, line := range []string{
+		"Time: 2024-01-15T14:30:00Z FPS: 30",
+		"Time: FPS: 30",
+		"FPS: 30",
+	} {
+		if got, ok := Uptime(line); ok {
+			t.Errorf("%q: uptime %v", line, got)
+		}
+	}
+}
+
 // Ensure we don't return (zero, false, err) for non-Time lines
 func TestParseTimeLine_NoErrorForNonTime(t *testing.T) {
 	_, ok, err := ParseTimeLine("2024/01/01 12:00:00 Some other log")
@@ -157,3 +189,20 @@ func TestParseTimeLine_ReplayFixture(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTimeLineAt_SourceTime(t *testing.T) {
+	src := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	snap, ok, err := ParseTimeLineAt("Time: 12.5 FPS: 30", src)
+	if !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	if !snap.Timestamp.Equal(src) {
+		t.Errorf("Timestamp: got %v, want source time %v", snap.Timestamp, src)
+	}
+
+	// A time inside the line wins over the source time.
+	snap, _, _ = ParseTimeLineAt("Time: 2024-01-15T14:30:00Z FPS: 45", src)
+	if snap.Timestamp.Year() != 2024 || snap.Timestamp.Month() != time.January {
+		t.Errorf("Timestamp: got %v, want line time", snap.Timestamp)
+	}
+}
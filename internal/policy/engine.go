@@ -2,10 +2,13 @@ package policy
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/analysis"
 	"github.com/mg7d/mg7d/internal/config"
 	"github.com/mg7d/mg7d/internal/state"
 )
@@ -15,6 +18,12 @@ type Engine struct {
 	instanceName string
 	cfg          config.Instance
 	fpsGuard     *FPSGuard
+	paused       map[string]bool // policies that skip evaluation
+	saved        *State          // last state written to cfg.Policy.StateFile
+	onError      func(error)     // state file write failures
+	notified     *State          // last state passed to onChange
+	onChange     func(*State)    // policy transitions
+	now          func() time.Time
 	mu           sync.Mutex
 }
 
@@ -23,6 +32,8 @@ func NewEngine(instanceName string, cfg config.Instance) *Engine {
 	e := &Engine{
 		instanceName: instanceName,
 		cfg:          cfg,
+		paused:       make(map[string]bool),
+		now:          time.Now,
 	}
 	if cfg.Policy.FPSGuard != nil && cfg.Policy.FPSGuard.Enabled {
 		e.fpsGuard = NewFPSGuard(instanceName, cfg.Policy.FPSGuard, cfg.Actions.ThrottleProfiles)
@@ -30,27 +41,214 @@ func NewEngine(instanceName string, cfg config.Instance) *Engine {
 	return e
 }
 
-// Evaluate runs policies on the snapshot and returns actions to apply.
+// Input is what policies see on each evaluation: the new snapshot and the trend
+// signals derived from the history up to and including it.
+type Input struct {
+	Snapshot state.Snapshot
+	Signals  analysis.Signals
+}
+
+// Evaluate runs policies on the input and returns actions to apply.
 // Only emits actions on state transitions (no repeated identical actions).
-func (e *Engine) Evaluate(snap state.Snapshot) []actions.Action {
+func (e *Engine) Evaluate(in Input) []actions.Action {
 	e.mu.Lock()
-	defer e.mu.Unlock()
 	var out []actions.Action
-	if e.fpsGuard != nil {
-		if a := e.fpsGuard.Evaluate(snap); a != nil {
+	if e.fpsGuard != nil && !e.paused[PolicyFPSGuard] {
+		if a := e.fpsGuard.Evaluate(in.Snapshot); a != nil {
 			out = append(out, a)
 		}
 	}
+	notify := e.commit()
+	e.mu.Unlock()
+	notify()
 	return out
 }
 
-var actionIDCounter int
-var actionIDMu sync.Mutex
+// FPSGuardStatus returns the FPS guard's status; ok is false if the guard is disabled.
+func (e *Engine) FPSGuardStatus(now time.Time) (st FPSGuardStatus, ok bool) {
+	if e.fpsGuard == nil {
+		return FPSGuardStatus{}, false
+	}
+	return e.fpsGuard.Status(now), true
+}
+
+// Policies returns the names of the enabled policies.
+func (e *Engine) Policies() []string {
+	var names []string
+	if e.fpsGuard != nil {
+		names = append(names, PolicyFPSGuard)
+	}
+	return names
+}
+
+// Paused returns the names of the paused policies, sorted.
+func (e *Engine) Paused() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pausedList()
+}
+
+// Pause stops evaluating the named policy until Resume. A paused policy sees no
+// snapshots and emits no actions; its state (e.g. the throttle step) is kept.
+func (e *Engine) Pause(name string) error {
+	return e.setPaused(name, true)
+}
+
+// Resume resumes evaluating a paused policy.
+func (e *Engine) Resume(name string) error {
+	return e.setPaused(name, false)
+}
+
+func (e *Engine) setPaused(name string, paused bool) error {
+	if !slices.Contains(e.Policies(), name) {
+		return fmt.Errorf("policy: unknown or disabled policy %q", name)
+	}
+	e.mu.Lock()
+	if paused {
+		e.paused[name] = true
+	} else {
+		delete(e.paused, name)
+	}
+	notify := e.commit()
+	e.mu.Unlock()
+	notify()
+	return nil
+}
+
+// Reset returns every policy to its unthrottled state, e.g. after a RestoreBaseline
+// forced through the API.
+func (e *Engine) Reset(now time.Time) {
+	e.mu.Lock()
+	if e.fpsGuard != nil {
+		e.fpsGuard.Reset(now)
+	}
+	notify := e.commit()
+	e.mu.Unlock()
+	notify()
+}
+
+// Callers hold e.mu.
+func (e *Engine) pausedList() []string {
+	var names []string
+	for name := range e.paused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-func newActionID() string {
-	actionIDMu.Lock()
-	actionIDCounter++
-	id := actionIDCounter
-	actionIDMu.Unlock()
-	return fmt.Sprintf("act-%d", id)
+// SetClock replaces time.Now as the source of the current time for every policy,
+// e.g. to replay a recorded log on its own timestamps. Call it before Evaluate.
+func (e *Engine) SetClock(now func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = now
+	if e.fpsGuard != nil {
+		e.fpsGuard.mu.Lock()
+		e.fpsGuard.now = now
+		e.fpsGuard.mu.Unlock()
+	}
+}
+
+// OnError sets the function called (may be nil) when the state file cannot be written.
+func (e *Engine) OnError(fn func(error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onError = fn
+}
+
+// OnChange sets the function called (may be nil) after a policy transition: the FPS
+// guard's throttle state or step changed, or a policy was paused or resumed. fn is
+// called without the engine locked, so it may call back into the engine.
+func (e *Engine) OnChange(fn func(*State)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onChange = fn
+}
+
+// commit saves the state file and returns the OnChange notification to run once
+// e.mu is released (a no-op when nothing changed). Callers hold e.mu.
+func (e *Engine) commit() func() {
+	e.saveState()
+	st := e.state()
+	if e.onChange == nil || st.equal(e.notified) {
+		return func() {}
+	}
+	e.notified = st
+	fn := e.onChange
+	return func() { fn(st) }
+}
+
+// State returns the engine's persistable policy state.
+func (e *Engine) State() *State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state()
+}
+
+// Callers hold e.mu.
+func (e *Engine) state() *State {
+	st := &State{Version: stateVersion, Instance: e.instanceName, Paused: e.pausedList()}
+	if e.fpsGuard != nil {
+		gs := e.fpsGuard.State()
+		st.FPSGuard = &gs
+	}
+	return st
+}
+
+// LoadState restores policy state from policy.state_file, if configured. If the
+// previous process left the server throttled and policy.restore_baseline_on_startup
+// is set, the guard is reset and a RestoreBaseline action is returned for the caller
+// to apply; otherwise the throttle resumes and restores once FPS is stable again.
+func (e *Engine) LoadState() ([]actions.Action, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	path := e.cfg.Policy.StateFile
+	if path == "" {
+		return nil, nil
+	}
+	st, err := LoadState(path)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	if st.Instance != e.instanceName {
+		return nil, fmt.Errorf("policy state: %s belongs to instance %q, not %q", path, st.Instance, e.instanceName)
+	}
+	e.saved = st
+	for _, name := range st.Paused {
+		if slices.Contains(e.Policies(), name) {
+			e.paused[name] = true
+		}
+	}
+	if e.fpsGuard == nil || st.FPSGuard == nil {
+		return nil, nil
+	}
+	e.fpsGuard.Restore(*st.FPSGuard)
+	if !st.FPSGuard.Throttled || !e.cfg.Policy.RestoreBaselineOnStartup {
+		return nil, nil
+	}
+	e.fpsGuard.Reset(e.now())
+	e.saveState()
+	return []actions.Action{e.fpsGuard.restoreAction("startup: agent restarted while throttled, restore baseline")}, nil
+}
+
+// saveState writes the state file when the state changed since the last write.
+// Callers hold e.mu.
+func (e *Engine) saveState() {
+	path := e.cfg.Policy.StateFile
+	if path == "" {
+		return
+	}
+	st := e.state()
+	if st.equal(e.saved) {
+		return
+	}
+	st.SavedAt = e.now()
+	if err := SaveState(path, st); err != nil {
+		if e.onError != nil {
+			e.onError(err)
+		}
+		return
+	}
+	e.saved = st
 }
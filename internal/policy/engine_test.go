@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+// TestEngineSetClock runs cooldown and restore windows of minutes on a virtual clock.
+func TestEngineSetClock(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "policy.json")
+	inst := config.Instance{
+		Policy: config.Policy{
+			FPSGuard: &config.FPSGuardPolicy{
+				Enabled:              true,
+				ThresholdLow:         25,
+				ThresholdRestore:     40,
+				RequireLowSamples:    1,
+				SampleWindowSamples:  1,
+				RestoreStableSeconds: 600,
+				CooldownSeconds:      300,
+				ThrottleProfile:      "default",
+			},
+			StateFile: statePath,
+		},
+		Actions: config.ActionsCfg{
+			ThrottleProfiles: map[string]config.ThrottleProfile{
+				"default": {Steps: []config.ThrottleStep{
+					{Pref: "MaxSpawnedZombies", Value: "30"},
+					{Pref: "MaxSpawnedZombies", Value: "20"},
+				}},
+			},
+		},
+	}
+	e := NewEngine("main", inst)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e.SetClock(func() time.Time { return now })
+
+	eval := func(fps float64, advance time.Duration) []actions.Action {
+		now = now.Add(advance)
+		return e.Evaluate(Input{Snapshot: state.Snapshot{FPS: fps, Timestamp: now}})
+	}
+	if got := eval(10, 0); len(got) != 1 {
+		t.Fatalf("throttle: %v", got)
+	}
+	if got := eval(10, 4*time.Minute); len(got) != 0 {
+		t.Fatalf("stepped within cooldown: %v", got)
+	}
+	if got := eval(10, time.Minute); len(got) != 1 {
+		t.Fatalf("no step after cooldown: %v", got)
+	}
+	// The stable window starts once the cooldown is over.
+	if got := eval(50, 5*time.Minute); len(got) != 0 {
+		t.Fatalf("restored at the start of the stable window: %v", got)
+	}
+	if got := eval(50, 9*time.Minute); len(got) != 0 {
+		t.Fatalf("restored before the stable window: %v", got)
+	}
+	got := eval(50, time.Minute)
+	if len(got) != 1 || got[0].Type() != "RestoreBaseline" {
+		t.Fatalf("restore: %v", got)
+	}
+
+	st, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.SavedAt.Equal(now) || !st.FPSGuard.LastAction.Equal(now) {
+		t.Errorf("state saved at %v, last action %v; want virtual time %v", st.SavedAt, st.FPSGuard.LastAction, now)
+	}
+}
+
+// TestEngineActionIDsSurviveRestart checks that an engine started after a restart does
+// not reuse action IDs already in the persisted audit log.
+func TestEngineActionIDsSurviveRestart(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	inst := config.Instance{
+		Policy: config.Policy{FPSGuard: &config.FPSGuardPolicy{
+			Enabled: true, ThresholdLow: 25, ThresholdRestore: 40,
+			RequireLowSamples: 1, SampleWindowSamples: 1, ThrottleProfile: "default",
+		}},
+		Actions: config.ActionsCfg{ThrottleProfiles: map[string]config.ThrottleProfile{
+			"default": {Steps: []config.ThrottleStep{{Pref: "MaxSpawnedZombies", Value: "30"}}},
+		}},
+	}
+	run := func() string {
+		l, err := state.OpenAuditLog(state.AuditLogOptions{Path: logPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		evs, err := l.ReadTail(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ring := state.NewAuditRing(100)
+		ring.Restore(evs)
+		ring.Persist(l, func(err error) { t.Error(err) })
+
+		got := NewEngine("main", inst).Evaluate(Input{Snapshot: state.Snapshot{FPS: 10}})
+		if len(got) != 1 {
+			t.Fatalf("throttle: %v", got)
+		}
+		ring.Append(state.AuditEvent{ActionID: got[0].ID(), ActionType: got[0].Type(), Status: "queued", QueuedAt: time.Now()})
+		return got[0].ID()
+	}
+	first, second := run(), run()
+	if first == second {
+		t.Fatalf("action ID %s reused after restart", first)
+	}
+	l, err := state.OpenAuditLog(state.AuditLogOptions{Path: logPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if evs, err := l.Query(state.AuditQuery{ActionID: second}); err != nil || len(evs) != 1 {
+		t.Errorf("Query(%s) = %d events, %v; want only the new action", second, len(evs), err)
+	}
+}
+
+// TestEngineOnChangeIgnoresTimers checks that only observable transitions notify and
+// rewrite the state file, not the stable-FPS window starting or resetting.
+func TestEngineOnChangeIgnoresTimers(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "policy.json")
+	inst := config.Instance{
+		Policy: config.Policy{
+			FPSGuard: &config.FPSGuardPolicy{
+				Enabled: true, ThresholdLow: 25, ThresholdRestore: 40,
+				RequireLowSamples: 1, SampleWindowSamples: 1, RestoreStableSeconds: 600,
+				ThrottleProfile: "default",
+			},
+			StateFile: statePath,
+		},
+		Actions: config.ActionsCfg{ThrottleProfiles: map[string]config.ThrottleProfile{
+			"default": {Steps: []config.ThrottleStep{{Pref: "MaxSpawnedZombies", Value: "30"}}},
+		}},
+	}
+	e := NewEngine("main", inst)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e.SetClock(func() time.Time { return now })
+	var changes int
+	e.OnChange(func(*State) { changes++ })
+
+	e.Evaluate(Input{Snapshot: state.Snapshot{FPS: 10}}) // throttle
+	saved, err := LoadState(statePath)
+	if err != nil || saved == nil {
+		t.Fatalf("state after throttle: %v %v", saved, err)
+	}
+	for _, fps := range []float64{50, 50, 30, 50} { // stable window starts, resets, starts
+		now = now.Add(time.Minute)
+		e.Evaluate(Input{Snapshot: state.Snapshot{FPS: fps}})
+	}
+	if changes != 1 {
+		t.Errorf("got %d change notifications, want 1 (the throttle)", changes)
+	}
+	if st, _ := LoadState(statePath); !st.SavedAt.Equal(saved.SavedAt) {
+		t.Errorf("state file rewritten at %v for a timer change", st.SavedAt)
+	}
+}
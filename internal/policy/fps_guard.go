@@ -10,6 +10,9 @@ import (
 	"github.com/mg7d/mg7d/internal/util"
 )
 
+// PolicyFPSGuard is the policy name recorded on FPSGuard actions.
+const PolicyFPSGuard = "fps_guard"
+
 // FPSGuard implements the FPS guardrail policy with hysteresis and cooldown.
 type FPSGuard struct {
 	instanceName string
@@ -22,22 +25,33 @@ type FPSGuard struct {
 	throttled  bool
 	restoreAt  time.Time
 	lowSince   time.Time
+	now        func() time.Time
+	ids        *actions.IDSource
 	mu         sync.Mutex
 }
 
-// NewFPSGuard creates an FPS guard policy.
-func NewFPSGuard(instanceName string, cfg *config.FPSGuardPolicy, profiles map[string]config.ThrottleProfile) *FPSGuard {
+// FPSGuardDefaults returns cfg with the defaults NewFPSGuard applies.
+func FPSGuardDefaults(cfg config.FPSGuardPolicy) config.FPSGuardPolicy {
 	if cfg.SampleWindowSamples <= 0 {
 		cfg.SampleWindowSamples = 60
 	}
 	if cfg.RequireLowSamples <= 0 {
 		cfg.RequireLowSamples = 3
 	}
+	return cfg
+}
+
+// NewFPSGuard creates an FPS guard policy. Defaults (FPSGuardDefaults) are written
+// back to cfg.
+func NewFPSGuard(instanceName string, cfg *config.FPSGuardPolicy, profiles map[string]config.ThrottleProfile) *FPSGuard {
+	*cfg = FPSGuardDefaults(*cfg)
 	return &FPSGuard{
 		instanceName: instanceName,
 		cfg:          cfg,
 		profiles:     profiles,
 		fpsRing:      util.NewRing[float64](cfg.SampleWindowSamples),
+		now:          time.Now,
+		ids:          actions.NewIDSource("act"),
 	}
 }
 
@@ -46,7 +60,7 @@ func (g *FPSGuard) Evaluate(snap state.Snapshot) actions.Action {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	now := time.Now()
+	now := g.now()
 	g.fpsRing.Append(snap.FPS)
 
 	samples := g.fpsRing.Len()
@@ -83,22 +97,26 @@ func (g *FPSGuard) Evaluate(snap state.Snapshot) actions.Action {
 			g.lastAction = now
 			g.lastStep = 0
 			step := profile.Steps[0]
-			return actions.NewSetGamePref(
-				newActionID(), g.instanceName,
+			a := actions.NewSetGamePref(
+				g.ids.Next(), g.instanceName,
 				"fps_guardrail: FPS below threshold",
 				step.Pref, step.Value,
 			)
+			a.PolicyName = PolicyFPSGuard
+			return a
 		}
 		// Already throttled: consider next step
 		if g.lastStep+1 < len(profile.Steps) && now.Sub(g.lastAction) >= cooldown {
 			g.lastStep++
 			g.lastAction = now
 			step := profile.Steps[g.lastStep]
-			return actions.NewSetGamePref(
-				newActionID(), g.instanceName,
+			a := actions.NewSetGamePref(
+				g.ids.Next(), g.instanceName,
 				"fps_guardrail: stepping throttle",
 				step.Pref, step.Value,
 			)
+			a.PolicyName = PolicyFPSGuard
+			return a
 		}
 		return nil
 	}
@@ -114,10 +132,7 @@ func (g *FPSGuard) Evaluate(snap state.Snapshot) actions.Action {
 				g.throttled = false
 				g.restoreAt = time.Time{}
 				g.lastAction = now
-				return actions.NewRestoreBaseline(
-					newActionID(), g.instanceName,
-					"fps_guardrail: FPS stable, restore baseline",
-				)
+				return g.restoreAction("fps_guardrail: FPS stable, restore baseline")
 			}
 		} else {
 			g.restoreAt = time.Time{}
@@ -126,3 +141,84 @@ func (g *FPSGuard) Evaluate(snap state.Snapshot) actions.Action {
 
 	return nil
 }
+
+// State returns the guard's persistable state.
+func (g *FPSGuard) State() FPSGuardState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return FPSGuardState{
+		Throttled:  g.throttled,
+		Step:       g.lastStep,
+		Profile:    g.cfg.ThrottleProfile,
+		LastAction: g.lastAction,
+		RestoreAt:  g.restoreAt,
+		LowSince:   g.lowSince,
+	}
+}
+
+// FPSGuardStatus is the guard's state plus derived timers, for status reporting.
+type FPSGuardStatus struct {
+	FPSGuardState
+	Steps             int           // steps in the throttle profile
+	CooldownRemaining time.Duration // until another throttle step is allowed; 0 if none pending
+	RestoreRemaining  time.Duration // until RestoreBaseline if FPS stays high; 0 if the stable window is not running
+}
+
+// Status returns the guard's state with cooldown and restore timers relative to now.
+func (g *FPSGuard) Status(now time.Time) FPSGuardStatus {
+	st := FPSGuardStatus{FPSGuardState: g.State()}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st.Steps = len(g.profiles[g.cfg.ThrottleProfile].Steps)
+	if g.throttled {
+		cooldown := time.Duration(g.cfg.CooldownSeconds * float64(time.Second))
+		if left := g.lastAction.Add(cooldown).Sub(now); left > 0 {
+			st.CooldownRemaining = left
+		}
+		if !g.restoreAt.IsZero() {
+			stable := time.Duration(g.cfg.RestoreStableSeconds * float64(time.Second))
+			if left := g.restoreAt.Add(stable).Sub(now); left > 0 {
+				st.RestoreRemaining = left
+			}
+		}
+	}
+	return st
+}
+
+// Restore loads state saved by a previous process. The stable-FPS window restarts,
+// since FPS was not observed while the agent was down, and a step beyond the current
+// profile is clamped to its last step.
+func (g *FPSGuard) Restore(st FPSGuardState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.throttled = st.Throttled
+	g.lastStep = st.Step
+	if n := len(g.profiles[g.cfg.ThrottleProfile].Steps); g.lastStep >= n && n > 0 {
+		g.lastStep = n - 1
+	}
+	if g.lastStep < 0 {
+		g.lastStep = 0
+	}
+	g.lastAction = st.LastAction
+	g.lowSince = st.LowSince
+	g.restoreAt = time.Time{}
+}
+
+// Reset returns the guard to the unthrottled state, e.g. after a restore issued at
+// startup.
+func (g *FPSGuard) Reset(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.throttled = false
+	g.lastStep = 0
+	g.restoreAt = time.Time{}
+	g.lowSince = time.Time{}
+	g.lastAction = now
+}
+
+// restoreAction returns the RestoreBaseline action the guard emits.
+func (g *FPSGuard) restoreAction(reason string) *actions.RestoreBaseline {
+	a := actions.NewRestoreBaseline(g.ids.Next(), g.instanceName, reason)
+	a.PolicyName = PolicyFPSGuard
+	return a
+}
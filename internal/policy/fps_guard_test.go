@@ -59,10 +59,8 @@ func TestFPSGuard_ThrottleAndRestore(t *testing.T) {
 		t.Errorf("expected first step, got %s=%s", setPref.Pref, setPref.Value)
 	}
 
-	// Now feed stable high FPS for "RestoreStableSeconds" (2s) - we need to advance time
-	// The policy uses time.Now() so we can't easily fake time. Instead, feed many high FPS
-	// samples and rely on RestoreStableSeconds being 2s; we'd need to run for 2s real time.
-	// For unit test we just verify restore is not immediate.
+	// Now feed stable high FPS and verify restore is not immediate; the stable window
+	// itself is covered by TestFPSGuard_RestoreAfterStableWindow on a fake clock.
 	for i := 0; i < 5; i++ {
 		snap := state.Snapshot{FPS: 45}
 		a := g.Evaluate(snap)
@@ -75,6 +73,42 @@ func TestFPSGuard_ThrottleAndRestore(t *testing.T) {
 	}
 }
 
+func TestFPSGuard_RestoreAfterStableWindow(t *testing.T) {
+	cfg := &config.FPSGuardPolicy{
+		Enabled:              true,
+		ThresholdLow:         25,
+		ThresholdRestore:     40,
+		RequireLowSamples:    1,
+		SampleWindowSamples:  1,
+		RestoreStableSeconds: 60,
+		ThrottleProfile:      "default",
+	}
+	profiles := map[string]config.ThrottleProfile{
+		"default": {Steps: []config.ThrottleStep{{Pref: "MaxSpawnedZombies", Value: "30"}}},
+	}
+	g := NewFPSGuard("test", cfg, profiles)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+
+	if _, ok := g.Evaluate(state.Snapshot{FPS: 10}).(*actions.SetGamePref); !ok {
+		t.Fatal("expected throttle")
+	}
+	// The stable window starts with the first high sample and restarts after a dip.
+	for _, step := range []struct {
+		after time.Duration
+		fps   float64
+	}{{time.Second, 45}, {50 * time.Second, 45}, {5 * time.Second, 30}, {time.Second, 45}, {59 * time.Second, 45}} {
+		now = now.Add(step.after)
+		if a := g.Evaluate(state.Snapshot{FPS: step.fps}); a != nil {
+			t.Fatalf("at %v: unexpected %T", now, a)
+		}
+	}
+	now = now.Add(time.Second)
+	if a, ok := g.Evaluate(state.Snapshot{FPS: 45}).(*actions.RestoreBaseline); !ok {
+		t.Fatalf("expected RestoreBaseline after 60s stable, got %T", a)
+	}
+}
+
 func TestFPSGuard_NoActionWhenDisabled(t *testing.T) {
 	cfg := &config.FPSGuardPolicy{Enabled: false}
 	g := NewFPSGuard("test", cfg, nil)
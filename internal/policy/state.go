@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// stateVersion is bumped when the state file layout changes incompatibly.
+const stateVersion = 1
+
+// State is the persisted policy state of one instance, so a restarted agent knows it
+// left the server throttled.
+type State struct {
+	Version  int            `json:"version"`
+	Instance string         `json:"instance"`
+	SavedAt  time.Time      `json:"saved_at"`
+	FPSGuard *FPSGuardState `json:"fps_guard,omitempty"`
+	Paused   []string       `json:"paused,omitempty"` // policies paused through the API, sorted
+}
+
+// FPSGuardState is the FPSGuard state machine: throttle step, flag and timers.
+type FPSGuardState struct {
+	Throttled  bool      `json:"throttled"`
+	Step       int       `json:"step"` // index into the throttle profile's steps
+	Profile    string    `json:"profile"`
+	LastAction time.Time `json:"last_action"` // cooldown reference
+	RestoreAt  time.Time `json:"restore_at"`  // start of the current stable-FPS window
+	LowSince   time.Time `json:"low_since"`   // when the current throttle began
+}
+
+// equal reports whether s and o agree on what users can observe: throttle flag, step,
+// profile and paused policies. Timers are ignored, so a stable-FPS window starting or
+// resetting does not count as a transition or rewrite the state file; the file keeps
+// the timers of the last transition (Restore restarts the stable window anyway).
+func (s *State) equal(o *State) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	if s.Instance != o.Instance || (s.FPSGuard == nil) != (o.FPSGuard == nil) || !slices.Equal(s.Paused, o.Paused) {
+		return false
+	}
+	if s.FPSGuard == nil {
+		return true
+	}
+	a, b := s.FPSGuard, o.FPSGuard
+	return a.Throttled == b.Throttled && a.Step == b.Step && a.Profile == b.Profile
+}
+
+// LoadState reads a state file. A missing file returns (nil, nil).
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy state: %w", err)
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("policy state: %s: %w", path, err)
+	}
+	if st.Version != stateVersion {
+		return nil, fmt.Errorf("policy state: %s: unsupported version %d", path, st.Version)
+	}
+	return &st, nil
+}
+
+// SaveState writes st atomically (temp file, fsync, rename).
+func SaveState(path string, st *State) error {
+	st.Version = stateVersion
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("policy state: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("policy state: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("policy state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("policy state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("policy state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("policy state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("policy state: %w", err)
+	}
+	return nil
+}
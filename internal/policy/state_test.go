@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+func stateTestInstance(path string, restore bool) config.Instance {
+	return config.Instance{
+		Name: "main",
+		Policy: config.Policy{
+			FPSGuard: &config.FPSGuardPolicy{
+				Enabled:              true,
+				ThresholdLow:         25,
+				ThresholdRestore:     40,
+				RequireLowSamples:    3,
+				SampleWindowSamples:  10,
+				RestoreStableSeconds: 60,
+				CooldownSeconds:      0,
+				ThrottleProfile:      "default",
+			},
+			StateFile:                path,
+			RestoreBaselineOnStartup: restore,
+		},
+		Actions: config.ActionsCfg{ThrottleProfiles: map[string]config.ThrottleProfile{
+			"default": {Steps: []config.ThrottleStep{{Pref: "A", Value: "1"}, {Pref: "A", Value: "2"}}},
+		}},
+	}
+}
+
+func throttle(t *testing.T, e *Engine) {
+	t.Helper()
+	for i := 0; i < 4; i++ {
+		e.Evaluate(Input{Snapshot: state.Snapshot{FPS: 10}})
+	}
+	if st := e.State(); !st.FPSGuard.Throttled || st.FPSGuard.Step != 1 {
+		t.Fatalf("expected throttled at step 1, got %+v", st.FPSGuard)
+	}
+}
+
+func TestEngine_StateResumesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy-state.json")
+	throttle(t, NewEngine("main", stateTestInstance(path, false)))
+
+	e := NewEngine("main", stateTestInstance(path, false))
+	startup, err := e.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(startup) != 0 {
+		t.Errorf("resume mode should not emit startup actions: %v", startup)
+	}
+	if st := e.State(); !st.FPSGuard.Throttled || st.FPSGuard.Step != 1 || !st.FPSGuard.RestoreAt.IsZero() {
+		t.Errorf("restored state: %+v", st.FPSGuard)
+	}
+}
+
+func TestEngine_RestoreBaselineOnStartup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy-state.json")
+	throttle(t, NewEngine("main", stateTestInstance(path, true)))
+
+	e := NewEngine("main", stateTestInstance(path, true))
+	startup, err := e.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(startup) != 1 {
+		t.Fatalf("expected one startup action, got %v", startup)
+	}
+	if a, ok := startup[0].(*actions.RestoreBaseline); !ok || a.Policy() != PolicyFPSGuard {
+		t.Errorf("expected RestoreBaseline from fps_guard, got %#v", startup[0])
+	}
+	if e.State().FPSGuard.Throttled {
+		t.Error("guard should be reset after startup restore")
+	}
+	saved, err := LoadState(path)
+	if err != nil || saved == nil || saved.FPSGuard.Throttled {
+		t.Errorf("reset state should be saved: %v %+v", err, saved)
+	}
+
+	if _, err := NewEngine("other", stateTestInstance(path, true)).LoadState(); err == nil {
+		t.Error("state of another instance should be rejected")
+	}
+}
+
+func TestEngine_PausePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy-state.json")
+	e := NewEngine("main", stateTestInstance(path, false))
+	if err := e.Pause("nope"); err == nil {
+		t.Error("pausing an unknown policy succeeded")
+	}
+	if err := e.Pause(PolicyFPSGuard); err != nil {
+		t.Fatal(err)
+	}
+
+	e = NewEngine("main", stateTestInstance(path, false))
+	if _, err := e.LoadState(); err != nil {
+		t.Fatal(err)
+	}
+	if p := e.Paused(); len(p) != 1 || p[0] != PolicyFPSGuard {
+		t.Fatalf("paused after restart: %v", p)
+	}
+	for i := 0; i < 4; i++ {
+		if acts := e.Evaluate(Input{Snapshot: state.Snapshot{FPS: 10}}); len(acts) != 0 {
+			t.Fatalf("paused guard emitted %v", acts)
+		}
+	}
+}
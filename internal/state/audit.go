@@ -1,26 +1,101 @@
 package state
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/mg7d/mg7d/internal/util"
 )
 
-// AuditEvent records one action lifecycle (queued/sent/success/failure).
+// AuditEvent records one step of an action's lifecycle (queued/sent/success/failure/
+// dropped). Every event for an action repeats its description and the timestamps known
+// so far, so a single record is self-describing and all records of one action share
+// ActionID. Rejected API requests are recorded with ActionType APIAccess and Status
+// denied.
 type AuditEvent struct {
-	ActionID   string
-	ActionType string
-	Status     string // queued, sent, success, failure
-	Error      string
-	QueuedAt   time.Time
-	SentAt     time.Time
-	DoneAt     time.Time
+	Seq        uint64       `json:"seq"` // assigned by AuditRing.Append; increases by one per event
+	ActionID   string       `json:"action_id"`
+	ActionType string       `json:"action_type"`
+	Status     string       `json:"status"` // queued, sent, success, failure, dropped, denied
+	Error      string       `json:"error,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	Policy     string       `json:"policy,omitempty"` // emitting policy; empty for manual actions
+	Caller     string       `json:"caller,omitempty"` // API caller as scope@address; empty for policy actions
+	Reason     string       `json:"reason,omitempty"`
+	Changes    []PrefChange `json:"changes,omitempty"`  // game prefs the action sets
+	Commands   []string     `json:"commands,omitempty"` // telnet commands sent (or to be sent)
+	QueuedAt   time.Time    `json:"queued_at"`
+	SentAt     time.Time    `json:"sent_at"`
+	DoneAt     time.Time    `json:"done_at"`
+}
+
+// PrefChange is one game pref change. OldValue is the value the agent last set (or
+// the baseline); empty if unknown.
+type PrefChange struct {
+	Pref     string `json:"pref"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// Time returns when the event happened: the latest of DoneAt, SentAt and QueuedAt.
+func (e AuditEvent) Time() time.Time {
+	switch {
+	case !e.DoneAt.IsZero():
+		return e.DoneAt
+	case !e.SentAt.IsZero():
+		return e.SentAt
+	default:
+		return e.QueuedAt
+	}
+}
+
+// AuditSink receives every event appended to an AuditRing, in sequence order. Appends
+// to the ring wait for sink delivery (readers of the ring do not), so sinks that do I/O
+// over the network should buffer.
+type AuditSink interface {
+	Append(ev AuditEvent) error
+	Close() error
+}
+
+// AuditFilter selects the events a sink receives. Empty lists match everything.
+type AuditFilter struct {
+	Statuses    []string
+	ActionTypes []string
 }
 
-// AuditRing is a fixed-size ring buffer of audit events.
+// Match reports whether ev passes the filter.
+func (f AuditFilter) Match(ev AuditEvent) bool {
+	return matchList(f.Statuses, ev.Status) && matchList(f.ActionTypes, ev.ActionType)
+}
+
+func matchList(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, want := range list {
+		if want == v {
+			return true
+		}
+	}
+	return false
+}
+
+type auditSinkEntry struct {
+	name   string
+	sink   AuditSink
+	filter AuditFilter
+}
+
+// AuditRing is a fixed-size ring buffer of audit events that fans each event out to
+// its sinks (persistent log, webhook, syslog, ...).
 type AuditRing struct {
-	ring *util.Ring[AuditEvent]
+	ring    *util.Ring[AuditEvent]
+	mu      sync.Mutex
+	seq     uint64
+	sinks   []auditSinkEntry
+	onError func(error)
+	sinkMu  sync.Mutex // held during sink delivery; taken before mu is released to keep seq order
 }
 
 // NewAuditRing creates an audit ring with maxLen capacity.
@@ -28,9 +103,80 @@ func NewAuditRing(maxLen int) *AuditRing {
 	return &AuditRing{ring: util.NewRing[AuditEvent](maxLen)}
 }
 
-// Append adds an event to the ring.
+// Persist makes Append also write every event to log. onError (may be nil) is called
+// when a write fails; the event is still kept in memory.
+func (a *AuditRing) Persist(log *AuditLog, onError func(error)) {
+	a.OnError(onError)
+	a.AddSink("file", log, AuditFilter{})
+}
+
+// AddSink registers a sink that receives the events matching filter. name identifies
+// the sink in errors.
+func (a *AuditRing) AddSink(name string, sink AuditSink, filter AuditFilter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sinks = append(a.sinks, auditSinkEntry{name: name, sink: sink, filter: filter})
+}
+
+// OnError sets the function called (may be nil) when a sink fails to accept an event.
+func (a *AuditRing) OnError(fn func(error)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onError = fn
+}
+
+// Restore loads previously persisted events (oldest first) into the ring without
+// writing them again; sequence numbers continue after the newest one. Call before the
+// first Append.
+func (a *AuditRing) Restore(evs []AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ev := range evs {
+		a.ring.Append(ev)
+		if ev.Seq > a.seq {
+			a.seq = ev.Seq
+		}
+	}
+}
+
+// Append assigns the next sequence number, adds the event to the ring and passes it
+// to every sink whose filter matches. Sinks see events in sequence order; a failing
+// sink does not affect the others.
 func (a *AuditRing) Append(ev AuditEvent) {
+	a.mu.Lock()
+	a.seq++
+	ev.Seq = a.seq
 	a.ring.Append(ev)
+	sinks, onError := a.sinks, a.onError
+	a.sinkMu.Lock()
+	a.mu.Unlock()
+	defer a.sinkMu.Unlock()
+
+	for _, s := range sinks {
+		if !s.filter.Match(ev) {
+			continue
+		}
+		if err := s.sink.Append(ev); err != nil && onError != nil {
+			onError(fmt.Errorf("audit sink %s: %w", s.name, err))
+		}
+	}
+}
+
+// Close closes every sink.
+func (a *AuditRing) Close() error {
+	a.mu.Lock()
+	sinks := a.sinks
+	a.sinks = nil
+	a.sinkMu.Lock() // wait for an in-flight delivery
+	a.mu.Unlock()
+	defer a.sinkMu.Unlock()
+	var first error
+	for _, s := range sinks {
+		if err := s.sink.Close(); err != nil && first == nil {
+			first = fmt.Errorf("audit sink %s: %w", s.name, err)
+		}
+	}
+	return first
 }
 
 // CopyOut copies up to len(dst) recent events into dst, oldest first. Returns count.
@@ -42,3 +188,69 @@ func (a *AuditRing) CopyOut(dst []AuditEvent) int {
 func (a *AuditRing) Len() int {
 	return a.ring.Len()
 }
+
+// AuditQuery selects audit events. Zero fields match everything.
+type AuditQuery struct {
+	ActionID   string
+	ActionType string
+	Status     string
+	Instance   string
+	Policy     string
+	Since      time.Time // events at or after Since (by AuditEvent.Time)
+	Until      time.Time // events before Until
+	AfterSeq   uint64    // events with Seq > AfterSeq
+	BeforeSeq  uint64    // events with Seq < BeforeSeq
+}
+
+// Match reports whether ev satisfies the query.
+func (q AuditQuery) Match(ev AuditEvent) bool {
+	if q.ActionID != "" && ev.ActionID != q.ActionID {
+		return false
+	}
+	if q.ActionType != "" && ev.ActionType != q.ActionType {
+		return false
+	}
+	if q.Status != "" && ev.Status != q.Status {
+		return false
+	}
+	if q.Instance != "" && ev.Instance != q.Instance {
+		return false
+	}
+	if q.Policy != "" && ev.Policy != q.Policy {
+		return false
+	}
+	if ev.Seq <= q.AfterSeq || (q.BeforeSeq != 0 && ev.Seq >= q.BeforeSeq) {
+		return false
+	}
+	t := ev.Time()
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// OldestSeq returns the sequence number of the oldest event in the ring, or 0 if the
+// ring is empty.
+func (a *AuditRing) OldestSeq() uint64 {
+	buf := make([]AuditEvent, a.ring.Len())
+	if n := a.ring.CopyOut(buf); n > 0 {
+		return buf[0].Seq
+	}
+	return 0
+}
+
+// Query returns the events in the ring that match q, oldest first.
+func (a *AuditRing) Query(q AuditQuery) []AuditEvent {
+	buf := make([]AuditEvent, a.ring.Len())
+	buf = buf[:a.ring.CopyOut(buf)]
+	out := buf[:0]
+	for _, ev := range buf {
+		if q.Match(ev) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
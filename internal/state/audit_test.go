@@ -0,0 +1,119 @@
+package state
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditRing_QueryAndSeq(t *testing.T) {
+	base := time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC)
+	ring := NewAuditRing(16)
+	ring.Restore([]AuditEvent{{Seq: 41, ActionID: "act-0", Status: "success", DoneAt: base.Add(-time.Hour)}})
+	change := []PrefChange{{Pref: "MaxSpawnedZombies", OldValue: "64", NewValue: "48"}}
+	ring.Append(AuditEvent{ActionID: "act-1", Policy: "fps_guard", Status: "queued", Changes: change, QueuedAt: base})
+	ring.Append(AuditEvent{ActionID: "act-1", Policy: "fps_guard", Status: "sent", Changes: change, QueuedAt: base, SentAt: base.Add(time.Second)})
+	ring.Append(AuditEvent{ActionID: "act-2", Status: "queued", QueuedAt: base.Add(time.Minute)})
+
+	evs := ring.Query(AuditQuery{ActionID: "act-1"})
+	if len(evs) != 2 || evs[0].Seq != 42 || evs[1].Seq != 43 || evs[1].QueuedAt != base {
+		t.Fatalf("by action: got %+v", evs)
+	}
+	if evs := ring.Query(AuditQuery{Policy: "fps_guard"}); len(evs) != 2 {
+		t.Errorf("by policy: got %d events, want 2", len(evs))
+	}
+	evs = ring.Query(AuditQuery{Since: base, Until: base.Add(time.Minute)})
+	if len(evs) != 2 || evs[0].ActionID != "act-1" {
+		t.Errorf("by time: got %+v", evs)
+	}
+}
+
+func TestAuditLog_Query(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenAuditLog(AuditLogOptions{Path: path, MaxSizeBytes: 600, MaxBackups: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ring := NewAuditRing(2)
+	ring.Persist(l, func(err error) { t.Error(err) })
+	now := time.Now()
+	for _, status := range []string{"queued", "sent", "success"} {
+		ring.Append(AuditEvent{ActionID: "act-7", Status: status, Reason: "fps_guardrail: FPS below threshold", QueuedAt: now})
+		ring.Append(AuditEvent{ActionID: "act-8", Status: status, QueuedAt: now})
+	}
+	evs, err := l.Query(AuditQuery{ActionID: "act-7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 3 || evs[0].Status != "queued" || evs[2].Status != "success" || evs[2].Reason == "" {
+		t.Errorf("got %+v", evs)
+	}
+}
+
+// blockingSink records the Seq of every event; Append waits on gate when it is set.
+type blockingSink struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	seqs []uint64
+}
+
+func (s *blockingSink) Append(ev AuditEvent) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	s.seqs = append(s.seqs, ev.Seq)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestAuditRing_SlowSinkDoesNotBlockReaders(t *testing.T) {
+	ring := NewAuditRing(16)
+	sink := &blockingSink{gate: make(chan struct{})}
+	ring.AddSink("slow", sink, AuditFilter{})
+
+	done := make(chan struct{})
+	go func() {
+		ring.Append(AuditEvent{ActionID: "act-1", Status: "queued"})
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for ring.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// The sink is still blocked, yet the event is visible to readers.
+	if evs := ring.Query(AuditQuery{ActionID: "act-1"}); len(evs) != 1 || ring.OldestSeq() != 1 {
+		t.Fatalf("event not readable while the sink is busy: %+v", evs)
+	}
+	close(sink.gate)
+	<-done
+}
+
+func TestAuditRing_SinkOrderUnderConcurrency(t *testing.T) {
+	ring := NewAuditRing(256)
+	sink := &blockingSink{}
+	ring.AddSink("order", sink, AuditFilter{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				ring.Append(AuditEvent{Status: "queued"})
+			}
+		}()
+	}
+	wg.Wait()
+	if len(sink.seqs) != 200 {
+		t.Fatalf("sink got %d events, want 200", len(sink.seqs))
+	}
+	for i, seq := range sink.seqs {
+		if seq != uint64(i+1) {
+			t.Fatalf("sink event %d has seq %d; events out of order", i, seq)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Persisted audit records are hash-chained: each line is the event's JSON with
+// "prev_hash" and "hash" appended as the last two keys, where
+//
+//	hash = SHA256(prev_hash + "\n" + event JSON)      (or HMAC-SHA256 with a key)
+//
+// and prev_hash is the hash of the previous record (empty for the first). The chain
+// continues across rotated files, so editing, removing or reordering a record breaks
+// it from that point on.
+
+const chainKey = `,"prev_hash":"`
+
+var chainSuffix = regexp.MustCompile(`^,"prev_hash":"([0-9a-f]{64})?","hash":"([0-9a-f]{64})"}$`)
+
+// chainHash returns the hex hash of one record.
+func chainHash(key []byte, prev string, event []byte) string {
+	var h interface {
+		Write([]byte) (int, error)
+		Sum([]byte) []byte
+	}
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	_, _ = h.Write([]byte(prev))
+	_, _ = h.Write([]byte{'\n'})
+	_, _ = h.Write(event)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chainRecord appends prev_hash and hash to the event JSON (which ends in '}').
+func chainRecord(event []byte, prev, hash string) []byte {
+	out := make([]byte, 0, len(event)+len(chainKey)+len(prev)+len(hash)+12)
+	out = append(out, event[:len(event)-1]...)
+	out = append(out, chainKey...)
+	out = append(out, prev...)
+	out = append(out, `","hash":"`...)
+	out = append(out, hash...)
+	return append(out, `"}`...)
+}
+
+// splitChained returns the event JSON and chain fields of a persisted line. ok is
+// false if the line carries no chain fields.
+func splitChained(line []byte) (event []byte, prev, hash string, ok bool) {
+	i := bytes.LastIndex(line, []byte(chainKey))
+	if i < 0 {
+		return nil, "", "", false
+	}
+	m := chainSuffix.FindSubmatch(line[i:])
+	if m == nil {
+		return nil, "", "", false
+	}
+	event = append(append(make([]byte, 0, i+1), line[:i]...), '}')
+	return event, string(m[1]), string(m[2]), true
+}
+
+// lastChainHash returns the hash of the newest chained record in files (oldest
+// first), or "" if there is none.
+func lastChainHash(files []string) string {
+	for i := len(files) - 1; i >= 0; i-- {
+		last := ""
+		_ = scanAuditLines(files[i], func(line []byte, _ int) {
+			if _, _, hash, ok := splitChained(line); ok {
+				last = hash
+			}
+		})
+		if last != "" {
+			return last
+		}
+	}
+	return ""
+}
+
+// scanAuditLines calls fn with every non-empty line of path and its 1-based number.
+func scanAuditLines(path string, fn func(line []byte, n int)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) > 0 {
+			fn(sc.Bytes(), n)
+		}
+	}
+	return sc.Err()
+}
+
+// AuditProblem is one finding of VerifyAuditLog.
+type AuditProblem struct {
+	File string
+	Line int
+	Seq  uint64
+	Msg  string
+}
+
+func (p AuditProblem) String() string {
+	return fmt.Sprintf("%s:%d (seq %d): %s", p.File, p.Line, p.Seq, p.Msg)
+}
+
+// AuditVerifyResult summarises a chain verification.
+type AuditVerifyResult struct {
+	Files     []string
+	Records   int            // chained records checked
+	Unchained int            // leading records written before chaining was enabled
+	Anchor    string         // prev_hash of the oldest retained record; non-empty once old backups were rotated away
+	Problems  []AuditProblem // broken chain: edited, removed, reordered or unchained records
+	Warnings  []AuditProblem // malformed lines, e.g. a line cut short by a crash
+}
+
+// OK reports whether the chain is intact.
+func (r AuditVerifyResult) OK() bool { return len(r.Problems) == 0 }
+
+// VerifyAuditLog checks the hash chain across path and its rotated backups (oldest
+// first). key must be the HMAC key the log was written with, or nil for plain SHA256.
+// Removing the newest records cannot be detected from the file alone.
+func VerifyAuditLog(path string, maxBackups int, key []byte) (AuditVerifyResult, error) {
+	res := AuditVerifyResult{Files: AuditFiles(path, maxBackups)}
+	if len(res.Files) == 0 {
+		return res, fmt.Errorf("audit verify: %s: no audit files", path)
+	}
+	prev := ""
+	chained := false
+	for _, file := range res.Files {
+		err := scanAuditLines(file, func(line []byte, n int) {
+			var head struct {
+				Seq uint64 `json:"seq"`
+			}
+			if err := json.Unmarshal(line, &head); err != nil {
+				res.Warnings = append(res.Warnings, AuditProblem{File: file, Line: n, Msg: "malformed line skipped"})
+				return
+			}
+			p := AuditProblem{File: file, Line: n, Seq: head.Seq}
+			event, recPrev, hash, ok := splitChained(line)
+			if !ok {
+				if chained {
+					p.Msg = "record has no hash (inserted or stripped)"
+					res.Problems = append(res.Problems, p)
+				} else {
+					res.Unchained++
+				}
+				return
+			}
+			res.Records++
+			if !chained {
+				res.Anchor = recPrev
+			} else if recPrev != prev {
+				p.Msg = "prev_hash does not match previous record (removed or reordered records)"
+				res.Problems = append(res.Problems, p)
+			}
+			chained = true
+			if chainHash(key, recPrev, event) != hash {
+				p.Msg = "hash mismatch (record edited, or wrong HMAC key)"
+				res.Problems = append(res.Problems, p)
+			}
+			prev = hash
+		})
+		if err != nil {
+			return res, fmt.Errorf("audit verify: %w", err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,325 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/util"
+)
+
+// Fsync modes for AuditLogOptions.Fsync.
+const (
+	FsyncAlways   = "always"   // fsync after every event
+	FsyncInterval = "interval" // fsync at most every FsyncInterval, and at most FsyncInterval after a write (default)
+	FsyncNever    = "never"    // leave flushing to the OS
+)
+
+const (
+	defaultAuditMaxSize       = 10 << 20
+	defaultAuditMaxBackups    = 5
+	defaultAuditFsyncInterval = time.Second
+)
+
+// AuditLogOptions configures a persistent audit log.
+type AuditLogOptions struct {
+	Path          string
+	MaxSizeBytes  int64         // rotate when the current file would exceed this; default 10MB
+	MaxAge        time.Duration // rotate when the current file's first event is older; 0 disables
+	MaxBackups    int           // rotated files kept (path.1 newest); default 5
+	Fsync         string        // always, interval (default), never
+	FsyncInterval time.Duration // default 1s
+	HMACKey       []byte        // signs the hash chain (HMAC-SHA256); plain SHA256 when empty
+}
+
+// AuditLog appends audit events to a JSONL file with size/age rotation. Disk use is
+// bounded by (MaxBackups+1) * MaxSizeBytes. Records are hash-chained across rotations
+// (see VerifyAuditLog).
+type AuditLog struct {
+	opts     AuditLogOptions
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time // time of the first event in the current file
+	lastSync time.Time
+	dirty    bool        // written since the last fsync (interval mode)
+	timer    *time.Timer // pending flush of dirty writes (interval mode)
+	lastHash string      // hash of the newest record; prev_hash of the next
+}
+
+// OpenAuditLog opens (or creates) the log at opts.Path for appending.
+func OpenAuditLog(opts AuditLogOptions) (*AuditLog, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("audit log: path required")
+	}
+	if opts.MaxSizeBytes <= 0 {
+		opts.MaxSizeBytes = defaultAuditMaxSize
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = defaultAuditMaxBackups
+	}
+	if opts.Fsync == "" {
+		opts.Fsync = FsyncInterval
+	}
+	if opts.FsyncInterval <= 0 {
+		opts.FsyncInterval = defaultAuditFsyncInterval
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	l := &AuditLog{opts: opts}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	l.lastHash = lastChainHash(AuditFiles(opts.Path, opts.MaxBackups))
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	f, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("audit log: %w", err)
+	}
+	l.f = f
+	l.size = info.Size()
+	l.openedAt = time.Time{}
+	if l.size > 0 {
+		_ = scanAuditFile(l.opts.Path, func(ev AuditEvent) bool {
+			l.openedAt = ev.Time()
+			return false
+		})
+		// Terminate a line cut short by a crash so the next event starts cleanly.
+		if last, err := readLastByte(l.opts.Path); err == nil && last != '\n' {
+			n, _ := f.Write([]byte{'\n'})
+			l.size += int64(n)
+		}
+	}
+	return nil
+}
+
+func readLastByte(path string) (byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	b := make([]byte, 1)
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.ReadAt(b, info.Size()-1); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// Append writes ev as one chained JSON line, rotating first if the size or age limit
+// is hit.
+func (l *AuditLog) Append(ev AuditEvent) error {
+	event, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return fmt.Errorf("audit log: closed")
+	}
+	hash := chainHash(l.opts.HMACKey, l.lastHash, event)
+	data := append(chainRecord(event, l.lastHash, hash), '\n')
+	if l.needsRotate(int64(len(data)), ev.Time()) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	l.lastHash = hash
+	if l.openedAt.IsZero() {
+		l.openedAt = ev.Time()
+	}
+	switch l.opts.Fsync {
+	case FsyncAlways:
+		return l.f.Sync()
+	case FsyncInterval:
+		if wait := l.opts.FsyncInterval - time.Since(l.lastSync); wait > 0 {
+			// Flush later even if no further event arrives.
+			l.dirty = true
+			if l.timer == nil {
+				l.timer = time.AfterFunc(wait, l.flushPending)
+			}
+			return nil
+		}
+		return l.syncLocked()
+	}
+	return nil
+}
+
+// syncLocked fsyncs the current file. l.mu must be held.
+func (l *AuditLog) syncLocked() error {
+	l.lastSync = time.Now()
+	l.dirty = false
+	return l.f.Sync()
+}
+
+// flushPending runs from the interval timer and fsyncs writes not yet synced.
+func (l *AuditLog) flushPending() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timer = nil
+	if l.f != nil && l.dirty {
+		_ = l.syncLocked()
+	}
+}
+
+func (l *AuditLog) needsRotate(next int64, at time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.size+next > l.opts.MaxSizeBytes {
+		return true
+	}
+	return l.opts.MaxAge > 0 && !l.openedAt.IsZero() && at.Sub(l.openedAt) >= l.opts.MaxAge
+}
+
+// rotate renames path -> path.1 -> path.2 ..., deleting the oldest beyond MaxBackups.
+func (l *AuditLog) rotate() error {
+	_ = l.syncLocked()
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	l.f = nil
+	_ = os.Remove(backupName(l.opts.Path, l.opts.MaxBackups))
+	for i := l.opts.MaxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(backupName(l.opts.Path, i), backupName(l.opts.Path, i+1))
+	}
+	if err := os.Rename(l.opts.Path, backupName(l.opts.Path, 1)); err != nil {
+		return fmt.Errorf("audit log: rotate: %w", err)
+	}
+	return l.open()
+}
+
+// Sync flushes the current file to disk.
+func (l *AuditLog) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	return l.syncLocked()
+}
+
+// Close syncs and closes the log.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	_ = l.f.Sync()
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// ReadTail returns up to n of the newest events across the current and rotated files,
+// oldest first.
+func (l *AuditLog) ReadTail(n int) ([]AuditEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ReadAuditTail(l.opts.Path, l.opts.MaxBackups, n)
+}
+
+// ReadAuditTail reads up to n of the newest events from path and its rotated backups
+// (path.1 .. path.maxBackups), oldest first. Malformed lines, e.g. a line cut short
+// by a crash, are skipped.
+func ReadAuditTail(path string, maxBackups, n int) ([]AuditEvent, error) {
+	ring := util.NewRing[AuditEvent](n)
+	for _, p := range AuditFiles(path, maxBackups) {
+		if err := scanAuditFile(p, func(ev AuditEvent) bool {
+			ring.Append(ev)
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]AuditEvent, ring.Len())
+	return out[:ring.CopyOut(out)], nil
+}
+
+// Query returns all persisted events (current and rotated files) that match q, oldest
+// first.
+func (l *AuditLog) Query(q AuditQuery) ([]AuditEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []AuditEvent
+	for _, p := range AuditFiles(l.opts.Path, l.opts.MaxBackups) {
+		if err := scanAuditFile(p, func(ev AuditEvent) bool {
+			if q.Match(ev) {
+				out = append(out, ev)
+			}
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// AuditFiles returns the existing files of a rotated log, oldest first.
+func AuditFiles(path string, maxBackups int) []string {
+	var files []string
+	for i := maxBackups; i >= 1; i-- {
+		if _, err := os.Stat(backupName(path, i)); err == nil {
+			files = append(files, backupName(path, i))
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files
+}
+
+// scanAuditFile decodes events from one file in order, stopping early when fn returns
+// false.
+func scanAuditFile(path string, fn func(AuditEvent) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var ev AuditEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			continue
+		}
+		if !fn(ev) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
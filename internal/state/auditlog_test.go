@@ -0,0 +1,123 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_RotateAndReadTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := OpenAuditLog(AuditLogOptions{Path: path, MaxSizeBytes: 400, MaxBackups: 2, Fsync: FsyncAlways})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 5, 1, 21, 3, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		ev := AuditEvent{ActionID: fmt.Sprintf("act-%d", i), ActionType: "SetGamePref", Status: "success", DoneAt: base.Add(time.Duration(i) * time.Second)}
+		if err := l.Append(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := AuditFiles(path, 2)
+	if len(files) != 3 {
+		t.Fatalf("expected current file plus 2 backups, got %v", files)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("backup beyond max_backups should be deleted")
+	}
+	for _, f := range files {
+		if info, _ := os.Stat(f); info.Size() > 400 {
+			t.Errorf("%s exceeds max size: %d", f, info.Size())
+		}
+	}
+
+	evs, err := ReadAuditTail(path, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 3 || evs[0].ActionID != "act-17" || evs[2].ActionID != "act-19" {
+		t.Errorf("tail: %+v", evs)
+	}
+}
+
+func TestAuditLog_ReopenAfterTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"action_id":"act-1","status":"success"}`+"\n"+`{"action_id":"act-2","sta`), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	l, err := OpenAuditLog(AuditLogOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(AuditEvent{ActionID: "act-3", Status: "queued", QueuedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	evs, err := l.ReadTail(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+	if len(evs) != 2 || evs[0].ActionID != "act-1" || evs[1].ActionID != "act-3" {
+		t.Errorf("expected the cut-off line to be skipped, got %+v", evs)
+	}
+}
+
+func TestAuditRing_PersistAndRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenAuditLog(AuditLogOptions{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := NewAuditRing(8)
+	ring.Persist(l, func(err error) { t.Error(err) })
+	ring.Append(AuditEvent{ActionID: "act-1", Status: "queued", QueuedAt: time.Now()})
+	_ = l.Close()
+
+	evs, err := ReadAuditTail(path, 5, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewAuditRing(8)
+	restored.Restore(evs)
+	if restored.Len() != 1 {
+		t.Errorf("restored %d events, want 1", restored.Len())
+	}
+}
+
+func TestAuditLog_IntervalFsyncFlushesWhenIdle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenAuditLog(AuditLogOptions{Path: path, Fsync: FsyncInterval, FsyncInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	pending := func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.dirty
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Append(AuditEvent{ActionID: fmt.Sprintf("act-%d", i), Status: "queued", QueuedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first event is synced at once; the second falls inside the interval.
+	if !pending() {
+		t.Fatal("second event should wait for the interval")
+	}
+	// No further Append: the timer flushes it.
+	deadline := time.Now().Add(time.Second)
+	for pending() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pending() {
+		t.Error("pending write not synced after the interval")
+	}
+}
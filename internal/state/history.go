@@ -0,0 +1,175 @@
+package state
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/util"
+)
+
+// Field names a numeric Snapshot field for history queries.
+type Field string
+
+// Snapshot fields available to history queries.
+const (
+	FieldFPS            Field = "fps"
+	FieldHeapMB         Field = "heap_mb"
+	FieldRSSMB          Field = "rss_mb"
+	FieldChunks         Field = "chunks"
+	FieldCGo            Field = "cgo"
+	FieldPlayers        Field = "players"
+	FieldZombies        Field = "zombies"
+	FieldEntities       Field = "entities"
+	FieldEntitiesActive Field = "entities_active"
+	FieldConnections    Field = "connections"
+)
+
+// Fields lists every queryable field.
+var Fields = []Field{
+	FieldFPS, FieldHeapMB, FieldRSSMB, FieldChunks, FieldCGo,
+	FieldPlayers, FieldZombies, FieldEntities, FieldEntitiesActive, FieldConnections,
+}
+
+// ParseField returns the Field named name.
+func ParseField(name string) (Field, error) {
+	for _, f := range Fields {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown snapshot field %q", name)
+}
+
+// Value returns the field's value in s. ok is false when the line did not report it
+// (CGo missing, entities_active -1) or the field is unknown.
+func (f Field) Value(s Snapshot) (v float64, ok bool) {
+	switch f {
+	case FieldFPS:
+		return s.FPS, true
+	case FieldHeapMB:
+		return s.HeapMB, true
+	case FieldRSSMB:
+		return s.RSSMB, true
+	case FieldChunks:
+		return float64(s.Chunks), true
+	case FieldCGo:
+		return float64(s.CGo), !s.CGoMissing
+	case FieldPlayers:
+		return float64(s.Players), true
+	case FieldZombies:
+		return float64(s.Zombies), true
+	case FieldEntities:
+		return float64(s.EntitiesTotal), true
+	case FieldEntitiesActive:
+		return float64(s.EntitiesActive), s.EntitiesActive >= 0
+	case FieldConnections:
+		return float64(s.CO), true
+	}
+	return 0, false
+}
+
+// Aggregate summarises one field over a time window.
+type Aggregate struct {
+	Field      Field
+	Count      int
+	Min        float64
+	Max        float64
+	Mean       float64
+	P50        float64
+	P95        float64
+	P99        float64
+	RatePerSec float64 // (last - first) / seconds between them; 0 with fewer than 2 samples
+	From       time.Time
+	To         time.Time
+}
+
+// History is a bounded, time-ordered store of recent snapshots shared by policies,
+// the API and analysis. Oldest snapshots are dropped when full.
+type History struct {
+	ring *util.Ring[Snapshot]
+}
+
+// NewHistory creates a history holding at most maxLen snapshots.
+func NewHistory(maxLen int) *History {
+	return &History{ring: util.NewRing[Snapshot](maxLen)}
+}
+
+// Add appends a snapshot. Snapshots are expected in Timestamp order.
+func (h *History) Add(s Snapshot) {
+	h.ring.Append(s)
+}
+
+// Len returns the number of stored snapshots.
+func (h *History) Len() int {
+	return h.ring.Len()
+}
+
+// Since returns stored snapshots with Timestamp after t, oldest first.
+func (h *History) Since(t time.Time) []Snapshot {
+	buf := make([]Snapshot, h.ring.Len())
+	buf = buf[:h.ring.CopyOut(buf)]
+	i := sort.Search(len(buf), func(i int) bool { return buf[i].Timestamp.After(t) })
+	return buf[i:]
+}
+
+// Window returns snapshots in (now-d, now], oldest first. now is a parameter so replays
+// can query on a virtual clock.
+func (h *History) Window(now time.Time, d time.Duration) []Snapshot {
+	snaps := h.Since(now.Add(-d))
+	end := sort.Search(len(snaps), func(i int) bool { return snaps[i].Timestamp.After(now) })
+	return snaps[:end]
+}
+
+// Aggregate computes min, max, mean, percentiles and rate of change of f over
+// (now-d, now]. ok is false if no snapshot in the window reports f.
+func (h *History) Aggregate(f Field, now time.Time, d time.Duration) (Aggregate, bool) {
+	return AggregateOf(f, h.Window(now, d))
+}
+
+// AggregateOf is Aggregate over an explicit, time-ordered slice of snapshots.
+func AggregateOf(f Field, snaps []Snapshot) (Aggregate, bool) {
+	agg := Aggregate{Field: f, Min: math.Inf(1), Max: math.Inf(-1)}
+	vals := make([]float64, 0, len(snaps))
+	var first, last Snapshot
+	var sum float64
+	for _, s := range snaps {
+		v, ok := f.Value(s)
+		if !ok {
+			continue
+		}
+		if len(vals) == 0 {
+			first = s
+		}
+		last = s
+		vals = append(vals, v)
+		sum += v
+		agg.Min = math.Min(agg.Min, v)
+		agg.Max = math.Max(agg.Max, v)
+	}
+	if len(vals) == 0 {
+		return Aggregate{Field: f}, false
+	}
+	agg.Count = len(vals)
+	agg.Mean = sum / float64(len(vals))
+	agg.From = first.Timestamp
+	agg.To = last.Timestamp
+	if secs := last.Timestamp.Sub(first.Timestamp).Seconds(); secs > 0 {
+		agg.RatePerSec = (vals[len(vals)-1] - vals[0]) / secs
+	}
+	sort.Float64s(vals)
+	agg.P50 = percentile(vals, 50)
+	agg.P95 = percentile(vals, 95)
+	agg.P99 = percentile(vals, 99)
+	return agg, true
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory_WindowAndAggregate(t *testing.T) {
+	h := NewHistory(100)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		h.Add(Snapshot{
+			Timestamp:      base.Add(time.Duration(i) * time.Minute),
+			FPS:            float64(10 * (i + 1)), // 10..100
+			HeapMB:         1000 + float64(i)*60,  // +60 MB/min
+			EntitiesActive: -1,
+		})
+	}
+	now := base.Add(9 * time.Minute)
+
+	if got := len(h.Window(now, 5*time.Minute)); got != 5 {
+		t.Errorf("5m window: got %d snapshots, want 5", got)
+	}
+
+	agg, ok := h.Aggregate(FieldFPS, now, time.Hour)
+	if !ok {
+		t.Fatal("expected aggregate")
+	}
+	if agg.Count != 10 || agg.Min != 10 || agg.Max != 100 || agg.Mean != 55 {
+		t.Errorf("count/min/max/mean: %+v", agg)
+	}
+	if agg.P50 != 50 || agg.P95 != 100 || agg.P99 != 100 {
+		t.Errorf("percentiles: p50=%v p95=%v p99=%v", agg.P50, agg.P95, agg.P99)
+	}
+
+	heap, _ := h.Aggregate(FieldHeapMB, now, time.Hour)
+	if heap.RatePerSec != 1 {
+		t.Errorf("heap rate: got %v MB/s, want 1", heap.RatePerSec)
+	}
+
+	if _, ok := h.Aggregate(FieldEntitiesActive, now, time.Hour); ok {
+		t.Error("entities_active is never reported; expected ok=false")
+	}
+	if _, ok := h.Aggregate(FieldFPS, base.Add(-time.Hour), time.Minute); ok {
+		t.Error("empty window should return ok=false")
+	}
+}
+
+func TestHistory_Bounded(t *testing.T) {
+	h := NewHistory(3)
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		h.Add(Snapshot{Timestamp: base.Add(time.Duration(i) * time.Second), FPS: float64(i)})
+	}
+	snaps := h.Since(time.Time{})
+	if len(snaps) != 3 || snaps[0].FPS != 2 || snaps[2].FPS != 4 {
+		t.Errorf("got %+v", snaps)
+	}
+}
+
+func TestParseField(t *testing.T) {
+	if f, err := ParseField("heap_mb"); err != nil || f != FieldHeapMB {
+		t.Errorf("heap_mb: %v %v", f, err)
+	}
+	if _, err := ParseField("bogus"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
@@ -2,6 +2,7 @@ package telnet
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"net"
@@ -11,22 +12,22 @@ import (
 
 // Config holds telnet client settings.
 type Config struct {
-	Host            string
-	Port            int
-	Password        string
-	RateLimitPerSec float64
-	CommandTimeout  time.Duration
-	ReconnectMin    time.Duration
-	ReconnectMax    time.Duration
-	CircuitBreakAfter int  // consecutive send failures before opening breaker
+	Host               string
+	Port               int
+	Password           string
+	RateLimitPerSec    float64
+	CommandTimeout     time.Duration
+	ReconnectMin       time.Duration
+	ReconnectMax       time.Duration
+	CircuitBreakAfter  int // consecutive send failures before opening breaker
 	CircuitBreakWindow time.Duration
 }
 
 const (
-	DefaultCommandTimeout   = 10 * time.Second
-	DefaultReconnectMin    = 2 * time.Second
-	DefaultReconnectMax    = 60 * time.Second
-	DefaultCircuitBreakAfter = 3
+	DefaultCommandTimeout     = 10 * time.Second
+	DefaultReconnectMin       = 2 * time.Second
+	DefaultReconnectMax       = 60 * time.Second
+	DefaultCircuitBreakAfter  = 3
 	DefaultCircuitBreakWindow = 30 * time.Second
 )
 
@@ -48,6 +49,12 @@ type Client struct {
 	breakerOpen bool
 	breakerAt   time.Time
 
+	// status (guarded by mu)
+	connectedAt   time.Time
+	authenticated bool // server accepted the password (always true without a password)
+	lastErr       error
+	lastErrAt     time.Time
+
 	// command queue: bounded
 	commands chan commandReq
 	done     chan struct{}
@@ -58,8 +65,8 @@ type commandReq struct {
 	result chan error
 }
 
-// NewClient creates a telnet client. Call Run to start the connection and send loop.
-func NewClient(cfg Config) *Client {
+// WithDefaults returns cfg with the defaults NewClient applies to zero values.
+func (cfg Config) WithDefaults() Config {
 	if cfg.RateLimitPerSec <= 0 {
 		cfg.RateLimitPerSec = 2.0
 	}
@@ -78,6 +85,12 @@ func NewClient(cfg Config) *Client {
 	if cfg.CircuitBreakWindow == 0 {
 		cfg.CircuitBreakWindow = DefaultCircuitBreakWindow
 	}
+	return cfg
+}
+
+// NewClient creates a telnet client. Call Run to start the connection and send loop.
+func NewClient(cfg Config) *Client {
+	cfg = cfg.WithDefaults()
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	return &Client{
 		cfg:      cfg,
@@ -125,6 +138,7 @@ func (c *Client) Run(ctx context.Context) {
 
 		conn, err := c.connect(ctx)
 		if err != nil {
+			c.setError(err)
 			if ctx.Err() != nil {
 				close(c.done)
 				return
@@ -141,10 +155,12 @@ func (c *Client) Run(ctx context.Context) {
 
 		c.mu.Lock()
 		c.conn = conn
-		c.mu.Unlock()
-		backoff = c.cfg.ReconnectMin
+		c.connectedAt = time.Now()
+		c.authenticated = c.cfg.Password == ""
 		c.failCount = 0
 		c.breakerOpen = false
+		c.mu.Unlock()
+		backoff = c.cfg.ReconnectMin
 
 		// Authenticate
 		if c.cfg.Password != "" {
@@ -170,20 +186,48 @@ func (c *Client) closeConn() {
 	if c.conn != nil {
 		_ = c.conn.Close()
 		c.conn = nil
+		c.authenticated = false
 	}
 }
 
+// Server messages answering the password prompt.
+var (
+	logonOK     = []byte("Logon successful")
+	logonFailed = []byte("Password incorrect")
+)
+
+// drain reads server output so the server doesn't block us, watching for the answer
+// to the password.
 func (c *Client) drain(conn net.Conn) {
-	r := bufio.NewReader(conn)
-	buf := make([]byte, 4096)
+	r := bufio.NewReaderSize(conn, 4096)
 	for {
-		_, err := r.Read(buf)
-		if err != nil {
+		line, err := r.ReadSlice('\n')
+		if len(line) > 0 {
+			c.observe(conn, line)
+		}
+		if err != nil && err != bufio.ErrBufferFull {
 			return
 		}
 	}
 }
 
+func (c *Client) observe(conn net.Conn, line []byte) {
+	ok, failed := bytes.Contains(line, logonOK), bytes.Contains(line, logonFailed)
+	if !ok && !failed {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != conn {
+		return
+	}
+	c.authenticated = ok
+	if failed {
+		c.lastErr = fmt.Errorf("telnet: password rejected")
+		c.lastErrAt = time.Now()
+	}
+}
+
 // takeToken blocks until one token is available or ctx done. Returns true if token acquired.
 func (c *Client) takeToken(ctx context.Context) bool {
 	c.tickMu.Lock()
@@ -253,6 +297,7 @@ func (c *Client) sendLoop(ctx context.Context, conn net.Conn) {
 				err = ctx.Err()
 			}
 			if err != nil {
+				c.setError(err)
 				c.mu.Lock()
 				c.failCount++
 				if c.failCount >= c.cfg.CircuitBreakAfter {
@@ -269,6 +314,54 @@ func (c *Client) sendLoop(ctx context.Context, conn net.Conn) {
 	}
 }
 
+func (c *Client) setError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastErr = err
+	c.lastErrAt = time.Now()
+}
+
+// Status is a point-in-time view of the connection, circuit breaker and command queue.
+type Status struct {
+	Addr                string
+	Connected           bool
+	Authenticated       bool      // password accepted (true without a password)
+	ConnectedAt         time.Time // when the current connection was established
+	BreakerOpen         bool
+	BreakerUntil        time.Time // when an open breaker lets commands through again
+	ConsecutiveFailures int
+	Queued              int // commands waiting to be sent
+	QueueCapacity       int
+	LastError           string
+	LastErrorAt         time.Time
+}
+
+// Status returns the client's current status.
+func (c *Client) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := Status{
+		Addr:                c.addr,
+		Connected:           c.conn != nil,
+		Authenticated:       c.conn != nil && c.authenticated,
+		BreakerOpen:         c.breakerOpen,
+		ConsecutiveFailures: c.failCount,
+		Queued:              len(c.commands),
+		QueueCapacity:       cap(c.commands),
+		LastErrorAt:         c.lastErrAt,
+	}
+	if st.Connected {
+		st.ConnectedAt = c.connectedAt
+	}
+	if c.breakerOpen {
+		st.BreakerUntil = c.breakerAt.Add(c.cfg.CircuitBreakWindow)
+	}
+	if c.lastErr != nil {
+		st.LastError = c.lastErr.Error()
+	}
+	return st
+}
+
 func (c *Client) sendOne(conn net.Conn, cmd Command) error {
 	c.mu.Lock()
 	if c.conn != conn {
@@ -71,3 +71,50 @@ func TestClient_ReconnectBackoff(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestClient_Authenticated(t *testing.T) {
+	for _, c := range []struct {
+		reply string
+		want  bool
+	}{
+		{"Logon successful.\r\n", true},
+		{"Password incorrect, please enter password:\r\n", false},
+	} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Skip("no listener:", err)
+		}
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			_, _ = conn.Write([]byte("Please enter password:\r\n"))
+			buf := make([]byte, 64)
+			_, _ = conn.Read(buf)
+			_, _ = conn.Write([]byte(c.reply))
+			time.Sleep(time.Second)
+		}()
+		client := NewClient(Config{Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, Password: "secret"})
+		ctx, cancel := context.WithCancel(context.Background())
+		go client.Run(ctx)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			st := client.Status()
+			if st.Connected && (st.Authenticated || st.LastError != "") {
+				if st.Authenticated != c.want {
+					t.Errorf("reply %q: authenticated=%v, last error %q", c.reply, st.Authenticated, st.LastError)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("reply %q: no result: %+v", c.reply, st)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		ln.Close()
+	}
+}
@@ -37,7 +37,7 @@ func (r *Ring[T]) Append(v T) {
 func (r *Ring[T]) CopyOut(dst []T) int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	n := r.Len()
+	n := r.len()
 	if n > len(dst) {
 		n = len(dst)
 	}
@@ -57,6 +57,12 @@ func (r *Ring[T]) CopyOut(dst []T) int {
 
 // Len returns current number of elements.
 func (r *Ring[T]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.len()
+}
+
+func (r *Ring[T]) len() int {
 	if r.full {
 		return r.maxLen
 	}
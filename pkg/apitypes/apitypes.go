@@ -0,0 +1,218 @@
+// Package apitypes holds the wire types of the mg7d agent's JSON API, described by
+// /api/v1/openapi.json. The agent (internal/api) encodes them and pkg/client decodes
+// them, so neither can drift from the other. It depends only on the standard library.
+//
+// Durations are in seconds.
+package apitypes
+
+import "time"
+
+// ErrorResponse is the body of every non-2xx JSON response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// StatusResponse is the body of GET /api/v1/status.
+type StatusResponse struct {
+	Instance      string         `json:"instance"`
+	Now           time.Time      `json:"now"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	Snapshot      *Snapshot      `json:"snapshot"` // null until the first Time line
+	Policy        PolicyStatus   `json:"policy"`
+	Telnet        *TelnetStatus  `json:"telnet"`  // null when telnet is not configured
+	Applier       *ApplierStatus `json:"applier"` // null when telnet is not configured
+	Source        *SourceStatus  `json:"source"`
+	Events        EventsStatus   `json:"events"`
+}
+
+// Snapshot is one parsed "Time:" line.
+type Snapshot struct {
+	Timestamp      time.Time `json:"timestamp"`
+	ParsedAt       time.Time `json:"parsed_at"`
+	AgeSeconds     float64   `json:"age_seconds"` // since parsed_at
+	FPS            float64   `json:"fps"`
+	HeapMB         float64   `json:"heap_mb"`
+	RSSMB          float64   `json:"rss_mb"`
+	Chunks         int       `json:"chunks"`
+	CGo            *int      `json:"cgo"` // null when the line had no CGO value
+	Players        int       `json:"players"`
+	Zombies        int       `json:"zombies"`
+	Entities       int       `json:"entities"`
+	EntitiesActive *int      `json:"entities_active"` // null when not reported
+	Connections    int       `json:"connections"`
+}
+
+// HealthResponse is the body of GET /api/v1/health.
+type HealthResponse struct {
+	Status     string                     `json:"status"` // ok, warn or fail: the worst component
+	Now        time.Time                  `json:"now"`
+	Components map[string]ComponentHealth `json:"components"` // source, parser, telnet, applier
+}
+
+// ComponentHealth is one component's health.
+type ComponentHealth struct {
+	Status     string  `json:"status"` // ok, warn or fail
+	Message    string  `json:"message"`
+	AgeSeconds float64 `json:"age_seconds,omitempty"` // since the last line or snapshot
+}
+
+// HistoryResponse is the body of GET /api/v1/history: one column per field, aligned
+// with Timestamps. Values are null where the Time line did not report the field.
+type HistoryResponse struct {
+	From       time.Time             `json:"from"`
+	To         time.Time             `json:"to"`
+	Samples    int                   `json:"samples"` // snapshots in the window, before thinning
+	Timestamps []time.Time           `json:"timestamps"`
+	Series     map[string][]*float64 `json:"series"`
+}
+
+// WhoamiResponse is the body of GET /api/v1/whoami.
+type WhoamiResponse struct {
+	Scope       string `json:"scope"` // read or admin
+	Caller      string `json:"caller"`
+	AuthEnabled bool   `json:"auth_enabled"`
+	Admin       bool   `json:"admin"` // admin endpoints are usable (requires api.auth_token)
+}
+
+// EventsStatus reports the event stream's clients.
+type EventsStatus struct {
+	Clients         int    `json:"clients"`
+	SlowDisconnects uint64 `json:"slow_disconnects"` // clients dropped for falling behind
+}
+
+// PlayersEvent is streamed when the player count changes between snapshots.
+type PlayersEvent struct {
+	Timestamp time.Time `json:"timestamp"` // of the snapshot with the new count
+	Players   int       `json:"players"`
+	Previous  int       `json:"previous"`
+	Delta     int       `json:"delta"`
+}
+
+// PolicyStatus reports each policy's state.
+type PolicyStatus struct {
+	Paused   []string        `json:"paused"`    // policies paused through the API
+	FPSGuard *FPSGuardStatus `json:"fps_guard"` // null when disabled
+}
+
+// FPSGuardStatus is the FPS guard's state machine.
+type FPSGuardStatus struct {
+	Throttled                bool      `json:"throttled"`
+	Step                     int       `json:"step"`
+	Steps                    int       `json:"steps"`
+	Profile                  string    `json:"profile"`
+	LastAction               time.Time `json:"last_action"`
+	ThrottledSince           time.Time `json:"throttled_since"`
+	CooldownRemainingSeconds float64   `json:"cooldown_remaining_seconds"`
+	RestoreTimerStarted      time.Time `json:"restore_timer_started"` // zero when FPS is not above threshold_restore
+	RestoreRemainingSeconds  float64   `json:"restore_remaining_seconds"`
+}
+
+// TelnetStatus is the telnet connection and circuit breaker state.
+type TelnetStatus struct {
+	Addr                string    `json:"addr"`
+	Connected           bool      `json:"connected"`
+	Authenticated       bool      `json:"authenticated"`
+	ConnectedAt         time.Time `json:"connected_at"`
+	BreakerOpen         bool      `json:"breaker_open"`
+	BreakerUntil        time.Time `json:"breaker_until"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Queued              int       `json:"queued"`
+	QueueCapacity       int       `json:"queue_capacity"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at"`
+}
+
+// ApplierStatus is the action queue depth.
+type ApplierStatus struct {
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+}
+
+// SourceStatus is the log source position and line queue.
+type SourceStatus struct {
+	Type       string    `json:"type"`
+	Path       string    `json:"path,omitempty"`
+	Open       bool      `json:"open"`
+	Offset     int64     `json:"offset"`
+	LastLineAt time.Time `json:"last_line_at"`
+	Queued     int       `json:"queued"`
+	Capacity   int       `json:"capacity"`
+	Dropped    uint64    `json:"dropped"`
+	Repaired   uint64    `json:"repaired"`
+}
+
+// AuditPage is the body of GET /api/v1/audit.
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"` // pass as cursor for the next page
+}
+
+// AuditEvent is one audit record.
+type AuditEvent struct {
+	Seq        uint64       `json:"seq"`
+	ActionID   string       `json:"action_id"`
+	ActionType string       `json:"action_type"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	Policy     string       `json:"policy,omitempty"`
+	Caller     string       `json:"caller,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	Changes    []PrefChange `json:"changes,omitempty"`
+	Commands   []string     `json:"commands,omitempty"`
+	QueuedAt   time.Time    `json:"queued_at"`
+	SentAt     time.Time    `json:"sent_at"`
+	DoneAt     time.Time    `json:"done_at"`
+}
+
+// PrefChange is one game preference change made by an action.
+type PrefChange struct {
+	Pref     string `json:"pref"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// PolicyRequest is the optional body of POST /api/v1/policies/{name}/pause and
+// .../resume.
+type PolicyRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PolicyStateResponse is the body returned by pause and resume.
+type PolicyStateResponse struct {
+	Policy string `json:"policy"`
+	Paused bool   `json:"paused"`
+}
+
+// RestoreBaselineRequest is the body of POST /api/v1/actions/restore-baseline.
+type RestoreBaselineRequest struct {
+	Reason string `json:"reason"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// SetGamePrefRequest is the body of POST /api/v1/actions/set-game-pref.
+type SetGamePrefRequest struct {
+	Pref   string `json:"pref"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// SayRequest is the body of POST /api/v1/actions/say.
+type SayRequest struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// ActionResponse describes a manual action: queued, or previewed with dry_run.
+type ActionResponse struct {
+	ActionID   string       `json:"action_id"`
+	ActionType string       `json:"action_type"`
+	Status     string       `json:"status"` // queued or dry_run
+	Caller     string       `json:"caller"`
+	Reason     string       `json:"reason"`
+	Commands   []string     `json:"commands"` // telnet commands sent (or that would be sent)
+	Changes    []PrefChange `json:"changes"`
+}
@@ -0,0 +1,263 @@
+// Package client is a Go client for the mg7d agent HTTP API described by
+// /api/v1/openapi.json (internal/api/openapi.json). Response and request types are
+// the server's own wire types from pkg/apitypes, so the client cannot drift from the
+// agent it is built with.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mg7d/mg7d/pkg/apitypes"
+)
+
+// Wire types, see pkg/apitypes.
+type (
+	StatusResponse         = apitypes.StatusResponse
+	Snapshot               = apitypes.Snapshot
+	PolicyStatus           = apitypes.PolicyStatus
+	FPSGuardStatus         = apitypes.FPSGuardStatus
+	TelnetStatus           = apitypes.TelnetStatus
+	ApplierStatus          = apitypes.ApplierStatus
+	SourceStatus           = apitypes.SourceStatus
+	EventsStatus           = apitypes.EventsStatus
+	HealthResponse         = apitypes.HealthResponse
+	ComponentHealth        = apitypes.ComponentHealth
+	HistoryResponse        = apitypes.HistoryResponse
+	WhoamiResponse         = apitypes.WhoamiResponse
+	AuditPage              = apitypes.AuditPage
+	AuditEvent             = apitypes.AuditEvent
+	PrefChange             = apitypes.PrefChange
+	PlayersEvent           = apitypes.PlayersEvent
+	PolicyStateResponse    = apitypes.PolicyStateResponse
+	RestoreBaselineRequest = apitypes.RestoreBaselineRequest
+	SetGamePrefRequest     = apitypes.SetGamePrefRequest
+	SayRequest             = apitypes.SayRequest
+	ActionResponse         = apitypes.ActionResponse
+)
+
+// Client calls one agent. It is safe for concurrent use.
+type Client struct {
+	base  *url.URL
+	token string
+	hc    *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sends token as "Authorization: Bearer <token>".
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient uses hc instead of a client with a 30s timeout, e.g. for TLS
+// settings. Streaming calls (AuditStream, Events) need a client without a timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.hc = hc }
+}
+
+// New returns a client for the agent at baseURL, e.g. "http://127.0.0.1:9090".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{base: u, hc: &http.Client{Timeout: 30 * time.Second}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is a non-2xx response from the agent.
+type Error struct {
+	StatusCode int
+	Message    string // the "error" field of the body, or the body itself
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("agent: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsStatus reports whether err is an *Error with the given status code.
+func IsStatus(err error, code int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == code
+}
+
+// Status returns GET /api/v1/status.
+func (c *Client) Status(ctx context.Context) (*StatusResponse, error) {
+	return getJSON[StatusResponse](ctx, c, "/api/v1/status", nil)
+}
+
+// Health returns GET /api/v1/health. A failing agent (503) is not an error: check
+// Status in the response.
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/health", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, errorOf(resp)
+	}
+	var v HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("client: health: %w", err)
+	}
+	return &v, nil
+}
+
+// Whoami returns GET /api/v1/whoami.
+func (c *Client) Whoami(ctx context.Context) (*WhoamiResponse, error) {
+	return getJSON[WhoamiResponse](ctx, c, "/api/v1/whoami", nil)
+}
+
+// HistoryQuery selects snapshots for History. Zero values use the server defaults.
+type HistoryQuery struct {
+	Window    time.Duration
+	Fields    []string
+	MaxPoints int
+}
+
+// History returns GET /api/v1/history.
+func (c *Client) History(ctx context.Context, q HistoryQuery) (*HistoryResponse, error) {
+	v := url.Values{}
+	if q.Window > 0 {
+		v.Set("window", q.Window.String())
+	}
+	if len(q.Fields) > 0 {
+		v.Set("fields", strings.Join(q.Fields, ","))
+	}
+	if q.MaxPoints > 0 {
+		v.Set("max_points", fmt.Sprint(q.MaxPoints))
+	}
+	return getJSON[HistoryResponse](ctx, c, "/api/v1/history", v)
+}
+
+// OpenAPI returns the agent's OpenAPI document.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	v, err := getJSON[json.RawMessage](ctx, c, "/api/v1/openapi.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	return *v, nil
+}
+
+// PausePolicy stops evaluating a policy (admin).
+func (c *Client) PausePolicy(ctx context.Context, name, reason string) (*PolicyStateResponse, error) {
+	return c.policy(ctx, name, "pause", reason)
+}
+
+// ResumePolicy resumes a paused policy (admin).
+func (c *Client) ResumePolicy(ctx context.Context, name, reason string) (*PolicyStateResponse, error) {
+	return c.policy(ctx, name, "resume", reason)
+}
+
+func (c *Client) policy(ctx context.Context, name, op, reason string) (*PolicyStateResponse, error) {
+	path := "/api/v1/policies/" + url.PathEscape(name) + "/" + op
+	return postJSON[PolicyStateResponse](ctx, c, path, apitypes.PolicyRequest{Reason: reason})
+}
+
+// RestoreBaseline queues a RestoreBaseline and resets the policies (admin).
+func (c *Client) RestoreBaseline(ctx context.Context, req RestoreBaselineRequest) (*ActionResponse, error) {
+	return postJSON[ActionResponse](ctx, c, "/api/v1/actions/restore-baseline", req)
+}
+
+// SetGamePref queues a manual SetGamePref (admin).
+func (c *Client) SetGamePref(ctx context.Context, req SetGamePrefRequest) (*ActionResponse, error) {
+	return postJSON[ActionResponse](ctx, c, "/api/v1/actions/set-game-pref", req)
+}
+
+// Say queues a manual Say (admin).
+func (c *Client) Say(ctx context.Context, req SayRequest) (*ActionResponse, error) {
+	return postJSON[ActionResponse](ctx, c, "/api/v1/actions/say", req)
+}
+
+func getJSON[T any](ctx context.Context, c *Client, path string, query url.Values) (*T, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := decode(resp, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func postJSON[T any](ctx context.Context, c *Client, path string, body any) (*T, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	resp, err := c.do(ctx, http.MethodPost, path, nil, bytes.NewReader(b), "")
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := decode(resp, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// do sends a request; the caller closes the body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, accept string) (*http.Response, error) {
+	u := *c.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept == "" {
+		accept = "application/json"
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// decode reads a 2xx JSON body into v, or returns an *Error.
+func decode(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errorOf(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("client: decode %s: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+func errorOf(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body apitypes.ErrorResponse
+	if json.Unmarshal(b, &body) != nil || body.Error == "" {
+		body.Error = strings.TrimSpace(string(b))
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: body.Error}
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mg7d/mg7d/internal/actions"
+	"github.com/mg7d/mg7d/internal/api"
+	"github.com/mg7d/mg7d/internal/config"
+	"github.com/mg7d/mg7d/internal/policy"
+	"github.com/mg7d/mg7d/internal/state"
+)
+
+type agent struct {
+	srv   *api.Server
+	ring  *state.AuditRing
+	snaps *state.SnapshotStore
+	url   string
+}
+
+func newAgent(t *testing.T) *agent {
+	t.Helper()
+	inst := config.Instance{
+		Name: "main",
+		Policy: config.Policy{FPSGuard: &config.FPSGuardPolicy{
+			Enabled: true, ThresholdLow: 25, ThresholdRestore: 40, RequireLowSamples: 3,
+			SampleWindowSamples: 10, RestoreStableSeconds: 120, CooldownSeconds: 60, ThrottleProfile: "default",
+		}},
+		Actions: config.ActionsCfg{ThrottleProfiles: map[string]config.ThrottleProfile{
+			"default": {Steps: []config.ThrottleStep{{Pref: "A", Value: "1"}}},
+		}},
+	}
+	ring := state.NewAuditRing(100)
+	applier := actions.NewApplier(nil, ring, 4)
+	applier.SetBaseline(map[string]string{"A": "5"})
+	snaps := state.NewSnapshotStore()
+	hist := state.NewHistory(100)
+	srv := api.NewServer("127.0.0.1:0", api.Deps{
+		Instance:  "main",
+		Snapshots: snaps,
+		History:   hist,
+		Policy:    policy.NewEngine("main", inst),
+		Applier:   applier,
+		Audit:     ring,
+		AuthToken: "admin-secret",
+		ReadToken: "read-secret",
+	})
+	ring.AddSink("events", srv.AuditSink(), state.AuditFilter{})
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		ts := now.Add(time.Duration(i-4) * time.Minute)
+		snap := state.Snapshot{Timestamp: ts, ParsedAt: ts, FPS: float64(30 + i), Players: i, EntitiesActive: -1}
+		snaps.Update(snap)
+		hist.Add(snap)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return &agent{srv: srv, ring: ring, snaps: snaps, url: ts.URL}
+}
+
+func newClient(t *testing.T, a *agent, token string) *Client {
+	t.Helper()
+	c, err := New(a.url+"/", WithToken(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientRead(t *testing.T) {
+	a := newAgent(t)
+	ctx := context.Background()
+	c := newClient(t, a, "read-secret")
+
+	st, err := c.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Instance != "main" || st.Snapshot == nil || st.Snapshot.FPS != 34 || st.Policy.FPSGuard == nil {
+		t.Errorf("status: %+v", st)
+	}
+	if h, err := c.Health(ctx); err != nil || h.Status == "" || len(h.Components) == 0 {
+		t.Errorf("health: %+v %v", h, err)
+	}
+	if w, err := c.Whoami(ctx); err != nil || w.Scope != "read" || w.Admin {
+		t.Errorf("whoami: %+v %v", w, err)
+	}
+	hist, err := c.History(ctx, HistoryQuery{Window: 10 * time.Minute, Fields: []string{"fps"}, MaxPoints: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hist.Samples != 5 || len(hist.Series["fps"]) != 2 || *hist.Series["fps"][1] != 34 {
+		t.Errorf("history: %+v", hist)
+	}
+	if doc, err := c.OpenAPI(ctx); err != nil || len(doc) == 0 {
+		t.Errorf("openapi: %v", err)
+	}
+
+	if _, err := newClient(t, a, "").Status(ctx); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("no token: %v", err)
+	}
+	if _, err := c.PausePolicy(ctx, "fps_guard", ""); !IsStatus(err, http.StatusForbidden) {
+		t.Errorf("pause with read token: %v", err)
+	}
+	if _, err := c.History(ctx, HistoryQuery{Fields: []string{"nope"}}); !IsStatus(err, http.StatusBadRequest) {
+		t.Errorf("bad field: %v", err)
+	} else if e := err.(*Error); e.Message == "" {
+		t.Errorf("error message missing: %+v", e)
+	}
+	if _, err := New("ftp://agent"); err == nil {
+		t.Error("New accepted a non-HTTP URL")
+	}
+}
+
+func TestClientAdminAndAudit(t *testing.T) {
+	a := newAgent(t)
+	ctx := context.Background()
+	c := newClient(t, a, "admin-secret")
+
+	p, err := c.PausePolicy(ctx, "fps_guard", "event night")
+	if err != nil || !p.Paused {
+		t.Fatalf("pause: %+v %v", p, err)
+	}
+	if p, err := c.ResumePolicy(ctx, "fps_guard", ""); err != nil || p.Paused {
+		t.Fatalf("resume: %+v %v", p, err)
+	}
+	if _, err := c.PausePolicy(ctx, "nope", ""); !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("unknown policy: %v", err)
+	}
+	res, err := c.SetGamePref(ctx, SetGamePrefRequest{Pref: "A", Value: "2", DryRun: true})
+	if err != nil || res.Status != "dry_run" || len(res.Commands) != 1 {
+		t.Fatalf("set-game-pref dry run: %+v %v", res, err)
+	}
+	if res, err := c.Say(ctx, SayRequest{Message: "hello"}); err != nil || res.Status != "queued" {
+		t.Fatalf("say: %+v %v", res, err)
+	}
+	if res, err := c.RestoreBaseline(ctx, RestoreBaselineRequest{DryRun: true}); err != nil || res.ActionType != "RestoreBaseline" {
+		t.Fatalf("restore-baseline: %+v %v", res, err)
+	}
+
+	// pause, resume, dry run, say (queued), dry run
+	page, err := c.Audit(ctx, AuditQuery{Limit: 2})
+	if err != nil || len(page.Events) != 2 || page.NextCursor == "" {
+		t.Fatalf("audit page 1: %+v %v", page, err)
+	}
+	page, err = c.Audit(ctx, AuditQuery{Limit: 10, Cursor: page.NextCursor})
+	if err != nil || len(page.Events) != 3 || page.NextCursor != "" {
+		t.Fatalf("audit page 2: %+v %v", page, err)
+	}
+	var types []string
+	err = c.AuditStream(ctx, AuditQuery{Status: "dry_run", Desc: true}, func(ev AuditEvent) error {
+		types = append(types, ev.ActionType)
+		return nil
+	})
+	if err != nil || len(types) != 2 || types[0] != "RestoreBaseline" {
+		t.Errorf("audit stream: %v %v", types, err)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	a := newAgent(t)
+	c := newClient(t, a, "read-secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	var got []Event
+	go func() {
+		done <- c.Events(ctx, []string{"snapshot", "audit"}, func(ev Event) error {
+			got = append(got, ev)
+			if len(got) == 2 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	for {
+		st, err := c.Status(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Events.Clients == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	now := time.Now()
+	a.srv.PublishSnapshot(state.Snapshot{Timestamp: now, ParsedAt: now, FPS: 12, EntitiesActive: -1})
+	a.ring.Append(state.AuditEvent{ActionID: "act-1", ActionType: "Say", Status: "queued"})
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("events: %v", err)
+	}
+	if len(got) != 2 || got[0].Type != "snapshot" || got[1].Type != "audit" || got[0].ID == 0 {
+		t.Fatalf("events: %+v", got)
+	}
+	var snap Snapshot
+	var ev AuditEvent
+	if err := got[0].Decode(&snap); err != nil || snap.FPS != 12 {
+		t.Errorf("snapshot: %+v %v", snap, err)
+	}
+	if err := got[1].Decode(&ev); err != nil || ev.ActionID != "act-1" {
+		t.Errorf("audit: %+v %v", ev, err)
+	}
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditQuery filters audit events. Zero values match everything.
+type AuditQuery struct {
+	ActionID   string
+	ActionType string
+	Status     string
+	Instance   string
+	Policy     string
+	Since      time.Time
+	Until      time.Time
+	Desc       bool   // newest first
+	Limit      int    // page size (Audit) or event cap (AuditStream); 0 = server default
+	Cursor     string // next_cursor of the previous page
+}
+
+func (q AuditQuery) values() url.Values {
+	v := url.Values{}
+	set := func(k, s string) {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	set("action_id", q.ActionID)
+	set("action_type", q.ActionType)
+	set("status", q.Status)
+	set("instance", q.Instance)
+	set("policy", q.Policy)
+	if !q.Since.IsZero() {
+		v.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		v.Set("until", q.Until.Format(time.RFC3339))
+	}
+	if q.Desc {
+		v.Set("order", "desc")
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	set("cursor", q.Cursor)
+	return v
+}
+
+// Audit returns one page of GET /api/v1/audit. Pass page.NextCursor as q.Cursor for
+// the next page; it is empty on the last page.
+func (c *Client) Audit(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	return getJSON[AuditPage](ctx, c, "/api/v1/audit", q.values())
+}
+
+// AuditStream reads every matching audit event as NDJSON and calls fn for each,
+// stopping at the first error fn returns.
+func (c *Client) AuditStream(ctx context.Context, q AuditQuery, fn func(AuditEvent) error) error {
+	v := q.values()
+	v.Set("format", "ndjson")
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/audit", v, nil, "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errorOf(resp)
+	}
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var ev AuditEvent
+		if err := dec.Decode(&ev); err != nil {
+			return fmt.Errorf("client: audit stream: %w", err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Event is one server-sent event from GET /api/v1/events. Data is the JSON payload:
+// a Snapshot, PlayersEvent, PolicyStatus or AuditEvent depending on Type.
+type Event struct {
+	ID   uint64
+	Type string // snapshot, players, policy or audit
+	Data json.RawMessage
+}
+
+// Decode unmarshals the payload into v.
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Events follows the event stream, calling fn for each event of the given types
+// (all types when empty). It returns when ctx is done, fn returns an error, or the
+// agent closes the stream; an agent that disconnects a slow client returns an
+// *Error with status 503. It does not reconnect.
+func (c *Client) Events(ctx context.Context, types []string, fn func(Event) error) error {
+	v := url.Values{}
+	if len(types) > 0 {
+		v.Set("types", strings.Join(types, ","))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/events", v, nil, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errorOf(resp)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	var ev Event
+	var data bytes.Buffer
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if ev.Type == "" || data.Len() == 0 {
+				continue
+			}
+			ev.Data = json.RawMessage(bytes.Clone(data.Bytes()))
+			if ev.Type == "error" {
+				var body struct{ Error string }
+				_ = ev.Decode(&body)
+				return &Error{StatusCode: http.StatusServiceUnavailable, Message: body.Error}
+			}
+			if err := fn(ev); err != nil {
+				return err
+			}
+			ev, data = Event{}, bytes.Buffer{}
+		case strings.HasPrefix(line, "id: "):
+			ev.ID, _ = strconv.ParseUint(line[4:], 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			ev.Type = line[7:]
+		case strings.HasPrefix(line, "data: "):
+			data.WriteString(line[6:])
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("client: events: %w", err)
+	}
+	return nil
+}